@@ -0,0 +1,252 @@
+package tfparser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ParseBody parses data written in terraform syntax, e.g. Packer or Nomad file, without any
+// schema. Result is a block without type, which attributes and blocks are the top level ones
+func ParseBody(data []byte, filename string) (*Block, error) {
+	p := newParser(string(data), filename, &TFconfig{})
+	body := &Block{Pos: Pos{filename, 1, 1}}
+	if err := p.popBlockBody(body, false); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// ParseBodyFile reads filename and parses it with ParseBody
+func ParseBodyFile(filename string) (*Block, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBody(data, filename)
+}
+
+/*
+Decode maps body into struct pointed by v using 'hcl' struct tags:
+
+	hcl:"name"           attribute, which is required
+	hcl:"name,optional"  attribute, which may be omitted
+	hcl:"name,block"     nested blocks of type name: struct for exactly one block, pointer to struct
+	                     for an optional one, slice of structs or pointers for any number of blocks
+	hcl:"name,label"     label of the block, labels are assigned in order of fields
+
+Attributes can be decoded into strings, bools, numbers, slices of them written as '[a, b]' and
+maps of them written as '{ key = value }'. Expressions which are not literals, e.g. 'var.name',
+can only be decoded into strings, as they are written. Attributes and blocks which are not in v
+are reported as errors
+*/
+func Decode(body *Block, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Decode expects pointer to struct, got %T", v)
+	}
+	return decodeBlock(body, rv.Elem())
+}
+
+type bodyField struct {
+	name  string
+	kind  string // empty for required attribute, 'optional', 'block' or 'label'
+	value reflect.Value
+}
+
+func decodeBlock(b *Block, rv reflect.Value) error {
+	var fields []bodyField
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		tag, ok := field.Tag.Lookup("hcl")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		f := bodyField{name: parts[0], value: rv.Field(i)}
+		if len(parts) > 1 {
+			f.kind = parts[1]
+		}
+		if !f.value.CanSet() {
+			return fmt.Errorf("Field %#q with tag %#q is not exported", field.Name, tag)
+		}
+		fields = append(fields, f)
+	}
+
+	known := make(map[string]bool)
+	labels := 0
+	for _, f := range fields {
+		switch f.kind {
+		case "label":
+			if f.value.Kind() != reflect.String {
+				return fmt.Errorf("Field for label %#q must be string, not %v", f.name, f.value.Type())
+			}
+			if labels >= len(b.Labels) {
+				return fmt.Errorf("%v: Missing label %#q of block %#q", b.Pos, f.name, b.Type)
+			}
+			f.value.SetString(b.Labels[labels])
+			labels++
+		case "block":
+			known[f.name] = true
+			var blocks []*Block
+			for _, nested := range b.Blocks {
+				if nested.Type == f.name {
+					blocks = append(blocks, nested)
+				}
+			}
+			if err := decodeBlocks(b, f, blocks); err != nil {
+				return err
+			}
+		case "", "optional":
+			known[f.name] = true
+			expr, exists := b.Attributes[f.name]
+			if !exists {
+				if f.kind == "" {
+					return fmt.Errorf("%v: Missing required attribute %#q", b.Pos, f.name)
+				}
+				continue
+			}
+			if err := decodeValue(expr, f.value); err != nil {
+				return fmt.Errorf("%v: Invalid value of attribute %#q: %v", b.AttributesPos[f.name], f.name, err)
+			}
+		default:
+			return fmt.Errorf("Unknown kind %#q in tag of field %#q", f.kind, f.name)
+		}
+	}
+	if labels != len(b.Labels) {
+		return fmt.Errorf("%v: Unexpected number of labels of block %#q, expected %v", b.Pos, b.Type, labels)
+	}
+	for _, name := range sortedStringKeys(b.Attributes) {
+		if !known[name] {
+			return fmt.Errorf("%v: Unsupported attribute %#q", b.AttributesPos[name], name)
+		}
+	}
+	for _, nested := range b.Blocks {
+		if !known[nested.Type] {
+			return fmt.Errorf("%v: Unsupported block %#q", nested.Pos, nested.Type)
+		}
+	}
+	return nil
+}
+
+// decodeBlocks decodes blocks of type f.name nested in b into field f
+func decodeBlocks(b *Block, f bodyField, blocks []*Block) error {
+	t := f.value.Type()
+	switch {
+	case t.Kind() == reflect.Struct:
+		if len(blocks) != 1 {
+			return fmt.Errorf("%v: Exactly one block %#q expected, found %v", b.Pos, f.name, len(blocks))
+		}
+		return decodeBlock(blocks[0], f.value)
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct:
+		if len(blocks) > 1 {
+			return fmt.Errorf("%v: At most one block %#q expected, found %v", b.Pos, f.name, len(blocks))
+		}
+		if len(blocks) == 1 {
+			f.value.Set(reflect.New(t.Elem()))
+			return decodeBlock(blocks[0], f.value.Elem())
+		}
+		return nil
+	case t.Kind() == reflect.Slice:
+		elem := t.Elem()
+		ptr := elem.Kind() == reflect.Ptr
+		if ptr {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			break
+		}
+		list := reflect.MakeSlice(t, 0, len(blocks))
+		for _, nested := range blocks {
+			item := reflect.New(elem)
+			if err := decodeBlock(nested, item.Elem()); err != nil {
+				return err
+			}
+			if !ptr {
+				item = item.Elem()
+			}
+			list = reflect.Append(list, item)
+		}
+		f.value.Set(list)
+		return nil
+	}
+	return fmt.Errorf("Field for block %#q must be struct, pointer to struct or slice of them, not %v", f.name, t)
+}
+
+// decodeValue decodes expression expr into v. Strings of attributes are unquoted with escapes
+// interpreted in Block already, strings in lists and objects are interpreted here
+func decodeValue(expr string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(expr)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(expr)
+		if err != nil {
+			return fmt.Errorf("bool expected, found %#q", expr)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(expr, 0, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("integer expected, found %#q", expr)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(expr, 0, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("unsigned integer expected, found %#q", expr)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(expr, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("number expected, found %#q", expr)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if !strings.HasPrefix(expr, "[") {
+			return fmt.Errorf("list expected, found %#q", expr)
+		}
+		p := newParser(expr, "", &TFconfig{})
+		items, err := p.popList()
+		if err != nil {
+			return err
+		}
+		if rest := p.data[p.i:]; rest != "" {
+			return fmt.Errorf("unexpected %#q after list", rest)
+		}
+		list := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(unescape(item), list.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(list)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || !strings.HasPrefix(expr, "{") {
+			return fmt.Errorf("object expected, found %#q", expr)
+		}
+		p := newParser(expr, "", &TFconfig{})
+		attrs, _, err := p.popAttributes(nil)
+		if err != nil {
+			return err
+		}
+		if rest := p.data[p.i:]; rest != "" {
+			return fmt.Errorf("unexpected %#q after object", rest)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(attrs))
+		for key, value := range attrs {
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(unquoteString(value), item); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), item)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
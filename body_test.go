@@ -0,0 +1,136 @@
+package tfparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+var bodyTestCode = `# nomad job
+job "web" {
+  datacenters = ["dc1", "dc2"]
+  meta        = "a \"quoted\" word\tand tab"
+  priority    = 50
+
+  group "frontend" {
+    count = 3
+
+    task "nginx" {
+      driver = "docker"
+      env    = { PORT = "8080", MODE = "prod", ARGS = "-c \"ls\"" }
+    }
+  }
+
+  update {
+    auto_revert = true
+  }
+}
+`
+
+type bodyTestTask struct {
+	Name   string            `hcl:"name,label"`
+	Driver string            `hcl:"driver"`
+	Env    map[string]string `hcl:"env,optional"`
+}
+
+type bodyTestGroup struct {
+	Name  string         `hcl:"name,label"`
+	Count int            `hcl:"count,optional"`
+	Tasks []bodyTestTask `hcl:"task,block"`
+}
+
+type bodyTestJob struct {
+	Name        string           `hcl:"name,label"`
+	Datacenters []string         `hcl:"datacenters"`
+	Meta        string           `hcl:"meta"`
+	Priority    int              `hcl:"priority,optional"`
+	Groups      []*bodyTestGroup `hcl:"group,block"`
+	Update      *struct {
+		AutoRevert bool `hcl:"auto_revert"`
+	} `hcl:"update,block"`
+}
+
+func TestParseBody(t *testing.T) {
+	body, err := ParseBody([]byte(bodyTestCode), "web.nomad")
+	if err != nil {
+		t.Fatalf("ParseBody returned an error, %v", err)
+	}
+	if len(body.Blocks) != 1 || body.Blocks[0].Type != "job" || body.Blocks[0].Labels[0] != "web" {
+		t.Fatalf("Unexpected top level blocks %+v", body.Blocks)
+	}
+	job := body.Blocks[0]
+	if job.Attributes["datacenters"] != `["dc1", "dc2"]` || job.AttributesPos["priority"] != (Pos{"web.nomad", 5, 3}) {
+		t.Errorf("Unexpected attributes %v at %v", job.Attributes, job.AttributesPos)
+	}
+
+	var config struct {
+		Job bodyTestJob `hcl:"job,block"`
+	}
+	if err := Decode(body, &config); err != nil {
+		t.Fatalf("Decode returned an error, %v", err)
+	}
+	j := config.Job
+	if j.Name != "web" || !reflect.DeepEqual(j.Datacenters, []string{"dc1", "dc2"}) || j.Priority != 50 {
+		t.Errorf("Unexpected job %+v", j)
+	}
+	if len(j.Groups) != 1 || j.Groups[0].Name != "frontend" || j.Groups[0].Count != 3 || len(j.Groups[0].Tasks) != 1 {
+		t.Fatalf("Unexpected groups %+v", j.Groups)
+	}
+	task := j.Groups[0].Tasks[0]
+	if task.Name != "nginx" || task.Driver != "docker" || !reflect.DeepEqual(task.Env, map[string]string{"PORT": "8080", "MODE": "prod", "ARGS": `-c "ls"`}) {
+		t.Errorf("Unexpected task %+v", task)
+	}
+	if j.Meta != "a \"quoted\" word\tand tab" {
+		t.Errorf("Escapes of string were not interpreted: %#q", j.Meta)
+	}
+	if j.Update == nil || !j.Update.AutoRevert {
+		t.Errorf("Unexpected update %+v", j.Update)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var job struct {
+		Job bodyTestJob `hcl:"job,block"`
+	}
+	for _, code := range []string{
+		`job "web" {}`,
+		`job "web" { datacenters = "dc1" }`,
+		"job \"web\" {\n datacenters = [] unknown\n}",
+		"job \"web\" {\n datacenters = []\n unknown = 1\n}",
+		"job \"web\" {\n datacenters = []\n priority = high\n}",
+		"job {\n datacenters = []\n}",
+		"job \"web\" {\n datacenters = []\n}\njob \"api\" {\n datacenters = []\n}",
+		"job \"web\" {\n datacenters = []\n update {}\n}",
+	} {
+		body, err := ParseBody([]byte(code), "")
+		if err != nil {
+			t.Fatalf("ParseBody returned an error for %#q, %v", code, err)
+		}
+		if err := Decode(body, &job); err == nil {
+			t.Errorf("Decode did not return an error for %#q", code)
+		}
+	}
+	if err := Decode(&Block{}, job); err == nil {
+		t.Error("Decode did not return an error for non-pointer")
+	}
+
+	body, err := ParseBody([]byte(`job "web" {}`), "")
+	if err != nil {
+		t.Fatalf("ParseBody returned an error, %v", err)
+	}
+	var intLabel struct {
+		Job struct {
+			Name int `hcl:"name,label"`
+		} `hcl:"job,block"`
+	}
+	if err := Decode(body, &intLabel); err == nil {
+		t.Error("Decode did not return an error for label of non-string field")
+	}
+	var unexported struct {
+		Job struct {
+			name string `hcl:"name,label"`
+		} `hcl:"job,block"`
+	}
+	if err := Decode(body, &unexported); err == nil {
+		t.Errorf("Decode did not return an error for unexported field, %v", unexported.Job.name)
+	}
+}
@@ -0,0 +1,463 @@
+/*
+Command tfparser prints information about terraform configuration parsed with package tfparser.
+
+Usage:
+
+	tfparser <command> [flags] [query] [path]
+
+path is a terraform file or a directory with *.tf files, current directory by default.
+Commands:
+
+	modules    list module calls
+	providers  list providers passed into module calls
+	sources    list module sources along with module calls using them
+	graph      print diagram of module calls
+	validate   check providers passed into module calls
+	query      print elements selected by query, see tfparser.Query for the syntax
+	lint       run lint rules, configured with -config file (.tfparser.json in path by default)
+	inputs     print values of input variables passed into module calls, variables are read from
+	           terraform.tfvars and *.auto.tfvars files in path and from -var-file files
+
+Exit code is 0 on success, 1 if validate or lint found issues and 2 if configuration could not be
+parsed or command line is invalid. Lint findings of 'info' severity do not change exit code.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/akabenin/tfparser"
+)
+
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitError    = 2
+)
+
+type command struct {
+	name         string
+	arg          string // name of required argument preceding path, if any
+	usage        string
+	formats      []string // the first one is default
+	configurable bool     // command accepts -config flag
+	vars         bool     // command accepts -var-file flag
+	run          func(config *tfparser.TFconfig, opts options, w io.Writer) (int, error)
+}
+
+// options of a command, parsed from command line
+type options struct {
+	arg      string // required argument, see command.arg
+	path     string
+	format   string
+	config   string   // file with command configuration
+	varFiles []string // files with variable definitions, in order of precedence from the lowest
+}
+
+// stringsFlag is a flag which may be set multiple times
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+var commands = []command{
+	{"modules", "", "list module calls", []string{"text", "json", "yaml", "csv"}, false, false, runModules},
+	{"providers", "", "list providers passed into module calls", []string{"text", "json"}, false, false, runProviders},
+	{"sources", "", "list module sources along with module calls using them", []string{"text", "json"}, false, false, runSources},
+	{"graph", "", "print diagram of module calls", []string{"dot", "mermaid"}, false, false, runGraph},
+	{"validate", "", "check providers passed into module calls", []string{"text", "json", "sarif"}, false, false, runValidate},
+	{"query", "query", "print elements selected by query", []string{"text", "json"}, false, false, runQuery},
+	{"lint", "", "run lint rules", []string{"text", "json", "sarif"}, true, false, runLint},
+	{"inputs", "", "print values of input variables passed into module calls", []string{"text", "json"}, false, true, runInputs},
+}
+
+// lintConfigFile is looked up in configuration directory if -config is not set
+const lintConfigFile = ".tfparser.json"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitError
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "tfparser: unknown command %#q\n", args[0])
+		usage(stderr)
+		return exitError
+	}
+
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var opts options
+	flags.StringVar(&opts.format, "format", cmd.formats[0], "output format: "+strings.Join(cmd.formats, ", "))
+	if cmd.configurable {
+		flags.StringVar(&opts.config, "config", "", "configuration file, "+lintConfigFile+" in path by default")
+	}
+	if cmd.vars {
+		flags.Var((*stringsFlag)(&opts.varFiles), "var-file", "file with variable definitions, may be repeated")
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: tfparser %v [flags] %v\n\n%v\n\nFlags:\n", cmd.name, strings.TrimSpace(cmd.arg+" [path]"), cmd.usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitError
+	}
+	if !contains(cmd.formats, opts.format) {
+		fmt.Fprintf(stderr, "tfparser: unsupported format %#q for %v, expected one of: %v\n", opts.format, cmd.name, strings.Join(cmd.formats, ", "))
+		return exitError
+	}
+	positional := flags.Args()
+	if cmd.arg != "" {
+		if len(positional) == 0 {
+			flags.Usage()
+			return exitError
+		}
+		opts.arg, positional = positional[0], positional[1:]
+	}
+	if len(positional) > 1 {
+		flags.Usage()
+		return exitError
+	}
+	opts.path = "."
+	if len(positional) == 1 {
+		opts.path = positional[0]
+	}
+
+	config, err := load(opts.path)
+	if err != nil {
+		fmt.Fprintf(stderr, "tfparser: %v\n", err)
+		return exitError
+	}
+	code, err := cmd.run(config, opts, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "tfparser: %v\n", err)
+		return exitError
+	}
+	return code
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: tfparser <command> [flags] [query] [path]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10v %v\n", cmd.name, cmd.usage)
+	}
+}
+
+// load parses path, which is either a file or a directory
+func load(path string) (*tfparser.TFconfig, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return tfparser.ParseDir(path)
+	}
+	return tfparser.ParseFile(path)
+}
+
+func runModules(config *tfparser.TFconfig, opts options, w io.Writer) (int, error) {
+	switch opts.format {
+	case "json":
+		return exitOK, tfparser.WriteJSON(w, config)
+	case "yaml":
+		return exitOK, tfparser.WriteYAML(w, config)
+	case "csv":
+		return exitOK, tfparser.WriteCSV(w, config)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tSOURCE\tPOSITION")
+	for _, name := range moduleNames(config) {
+		m := config.Modules[name]
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", name, m.SourcePath, m.Pos)
+	}
+	return exitOK, tw.Flush()
+}
+
+type providerMapping struct {
+	Module   string `json:"module"`
+	Alias    string `json:"alias"`
+	Provider string `json:"provider"`
+}
+
+func runProviders(config *tfparser.TFconfig, opts options, w io.Writer) (int, error) {
+	mappings := []providerMapping{}
+	for _, name := range moduleNames(config) {
+		m := config.Modules[name]
+		aliases := make([]string, 0, len(m.Providers))
+		for alias := range m.Providers {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+		for _, alias := range aliases {
+			mappings = append(mappings, providerMapping{name, alias, m.Providers[alias]})
+		}
+	}
+	if opts.format == "json" {
+		return exitOK, writeJSON(w, mappings)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tALIAS\tPROVIDER")
+	for _, m := range mappings {
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", m.Module, m.Alias, m.Provider)
+	}
+	return exitOK, tw.Flush()
+}
+
+func runSources(config *tfparser.TFconfig, opts options, w io.Writer) (int, error) {
+	sources := make(map[string][]string)
+	for _, name := range moduleNames(config) {
+		src := config.Modules[name].SourcePath
+		sources[src] = append(sources[src], name)
+	}
+	if opts.format == "json" {
+		return exitOK, writeJSON(w, sources)
+	}
+	list := make([]string, 0, len(sources))
+	for src := range sources {
+		list = append(list, src)
+	}
+	sort.Strings(list)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tMODULES")
+	for _, src := range list {
+		fmt.Fprintf(tw, "%v\t%v\n", src, strings.Join(sources[src], ","))
+	}
+	return exitOK, tw.Flush()
+}
+
+func runGraph(config *tfparser.TFconfig, opts options, w io.Writer) (int, error) {
+	if opts.format == "mermaid" {
+		return exitOK, tfparser.WriteMermaid(w, config)
+	}
+	return exitOK, tfparser.WriteDOT(w, config)
+}
+
+func runValidate(config *tfparser.TFconfig, opts options, w io.Writer) (int, error) {
+	modules, err := tfparser.ParseModules(config, configDir(opts.path))
+	if err != nil {
+		return exitError, err
+	}
+	issues := tfparser.CheckProviders(config, modules)
+	switch opts.format {
+	case "json":
+		if issues == nil {
+			issues = []tfparser.ProviderIssue{}
+		}
+		err = writeJSON(w, issues)
+	case "sarif":
+		findings := make([]tfparser.Finding, 0, len(issues))
+		for _, issue := range issues {
+			findings = append(findings, issue.Finding())
+		}
+		rules := []tfparser.Rule{tfparser.NewProviderWiringRule(modules)}
+		err = tfparser.WriteSARIF(w, configDir(opts.path), rules, findings, readSources(findings))
+	default:
+		for _, issue := range issues {
+			fmt.Fprintln(w, issue)
+		}
+	}
+	if err != nil {
+		return exitError, err
+	}
+	if len(issues) > 0 {
+		return exitFindings, nil
+	}
+	return exitOK, nil
+}
+
+func runQuery(config *tfparser.TFconfig, opts options, w io.Writer) (int, error) {
+	results, err := tfparser.Select(config, opts.arg)
+	if err != nil {
+		return exitError, err
+	}
+	if opts.format == "json" {
+		if results == nil {
+			results = []tfparser.QueryResult{}
+		}
+		return exitOK, writeJSON(w, results)
+	}
+	for _, r := range results {
+		value, ok := r.Value.(string)
+		if !ok {
+			data, err := json.Marshal(r.Value)
+			if err != nil {
+				return exitError, err
+			}
+			value = string(data)
+		}
+		fmt.Fprintf(w, "%v\t%v\n", r.Path, value)
+	}
+	return exitOK, nil
+}
+
+func runLint(config *tfparser.TFconfig, opts options, w io.Writer) (int, error) {
+	linter := tfparser.NewLinter()
+	configFile := opts.config
+	if configFile == "" {
+		configFile = filepath.Join(configDir(opts.path), lintConfigFile)
+		if _, err := os.Stat(configFile); os.IsNotExist(err) {
+			configFile = ""
+		}
+	}
+	if configFile != "" {
+		lintConfig, err := tfparser.ReadLintConfig(configFile)
+		if err != nil {
+			return exitError, err
+		}
+		if err := linter.Configure(lintConfig); err != nil {
+			return exitError, err
+		}
+	}
+	findings := linter.Lint(config)
+	var err error
+	switch opts.format {
+	case "json":
+		if findings == nil {
+			findings = []tfparser.Finding{}
+		}
+		err = writeJSON(w, findings)
+	case "sarif":
+		err = tfparser.WriteSARIF(w, configDir(opts.path), linter.Rules(), findings, readSources(findings))
+	default:
+		for _, f := range findings {
+			fmt.Fprintln(w, f)
+		}
+	}
+	if err != nil {
+		return exitError, err
+	}
+	for _, f := range findings {
+		if f.Severity > tfparser.SeverityInfo {
+			return exitFindings, nil
+		}
+	}
+	return exitOK, nil
+}
+
+func runInputs(config *tfparser.TFconfig, opts options, w io.Writer) (int, error) {
+	dir := configDir(opts.path)
+	vars, err := loadVars(dir, opts.varFiles)
+	if err != nil {
+		return exitError, err
+	}
+	modules, err := tfparser.ParseModules(config, dir)
+	if err != nil {
+		return exitError, err
+	}
+	inputs, err := tfparser.EffectiveInputs(config, modules, vars)
+	if err != nil {
+		return exitError, err
+	}
+	if opts.format == "json" {
+		if inputs == nil {
+			inputs = []*tfparser.ModuleInput{}
+		}
+		return exitOK, writeJSON(w, inputs)
+	}
+	for _, in := range inputs {
+		fmt.Fprintln(w, in)
+	}
+	return exitOK, nil
+}
+
+// loadVars reads variable definitions as terraform does: terraform.tfvars, terraform.tfvars.json
+// and *.auto.tfvars(.json) files of dir in lexical order, then varFiles. Later files take precedence
+func loadVars(dir string, varFiles []string) (map[string]tfparser.Value, error) {
+	files := []string{filepath.Join(dir, "terraform.tfvars"), filepath.Join(dir, "terraform.tfvars.json")}
+	auto, err := filepath.Glob(filepath.Join(dir, "*.auto.tfvars"))
+	if err != nil {
+		return nil, err
+	}
+	autoJSON, err := filepath.Glob(filepath.Join(dir, "*.auto.tfvars.json"))
+	if err != nil {
+		return nil, err
+	}
+	auto = append(auto, autoJSON...)
+	sort.Strings(auto)
+	vars := make(map[string]tfparser.Value)
+	for i, filename := range append(append(files, auto...), varFiles...) {
+		// default files are optional, -var-file ones are not
+		if _, err := os.Stat(filename); os.IsNotExist(err) && i < len(files) {
+			continue
+		}
+		fileVars, err := tfparser.ParseVarsFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		for name, v := range fileVars {
+			vars[name] = v
+		}
+	}
+	return vars, nil
+}
+
+// readSources reads files findings refer to, so that SARIF regions end with the attribute or block
+// of a finding. Files which can not be read are left out
+func readSources(findings []tfparser.Finding) map[string][]byte {
+	sources := make(map[string][]byte)
+	for _, f := range findings {
+		if _, read := sources[f.Pos.Filename]; read || f.Pos.Filename == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(f.Pos.Filename)
+		if err == nil {
+			sources[f.Pos.Filename] = data
+		}
+	}
+	return sources
+}
+
+// configDir returns directory of configuration path, which is either a file or a directory
+func configDir(path string) string {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return filepath.Dir(path)
+	}
+	return path
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func moduleNames(config *tfparser.TFconfig) []string {
+	names := make([]string, 0, len(config.Modules))
+	for name := range config.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunModules(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := run([]string{"modules", "../../testdata/tf"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Unexpected exit code %v, stderr: %v", code, stderr.String())
+	}
+	expected := `MODULE   SOURCE                     POSITION
+module1  ../../modules/module_name  ../../testdata/tf/main.tf:4:1
+module2  ../../modules/vpc-routing  ../../testdata/tf/main.tf:29:1
+`
+	if stdout.String() != expected {
+		t.Fatalf("Unexpected output:\n%v\nexpected:\n%v", stdout.String(), expected)
+	}
+}
+
+func TestRunSourcesJSON(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := run([]string{"sources", "-format", "json", "../../testdata/tf/main.tf"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Unexpected exit code %v, stderr: %v", code, stderr.String())
+	}
+	expected := `{
+  "../../modules/module_name": [
+    "module1"
+  ],
+  "../../modules/vpc-routing": [
+    "module2"
+  ]
+}
+`
+	if stdout.String() != expected {
+		t.Fatalf("Unexpected output:\n%v\nexpected:\n%v", stdout.String(), expected)
+	}
+}
+
+func TestRunValidateFindings(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := run([]string{"validate", "../../testdata/providers/root"}, &stdout, &stderr)
+	if code != exitFindings {
+		t.Fatalf("Unexpected exit code %v, expected %v, stderr: %v", code, exitFindings, stderr.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 3 {
+		t.Fatalf("Unexpected number of issues reported:\n%v", stdout.String())
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"unknown"},
+		{"graph", "-format", "json"},
+		{"query"},
+		{"query", "module[", "../../testdata/tf"},
+		{"modules", "../../testdata/does-not-exist"},
+	}
+	for _, args := range tests {
+		var stdout, stderr strings.Builder
+		if code := run(args, &stdout, &stderr); code != exitError {
+			t.Fatalf("Unexpected exit code %v for %v, expected %v", code, args, exitError)
+		}
+	}
+}
+
+func TestRunQuery(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := run([]string{"query", `module[source~="vpc-routing"].providers["aws.bob"]`, "../../testdata/tf"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Unexpected exit code %v, stderr: %v", code, stderr.String())
+	}
+	expected := "modules[\"module2\"].providers[\"aws.bob\"]\taws.ap-southeast-2\n"
+	if stdout.String() != expected {
+		t.Fatalf("Unexpected output %#q, expected %#q", stdout.String(), expected)
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := run([]string{"lint", "../../testdata/lint"}, &stdout, &stderr)
+	if code != exitFindings {
+		t.Fatalf("Unexpected exit code %v, expected %v, stderr: %v", code, exitFindings, stderr.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 8 {
+		t.Fatalf("Unexpected number of findings reported:\n%v", stdout.String())
+	}
+}
+
+func TestRunLintNoFindings(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := run([]string{"lint", "../../testdata/tf"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Unexpected exit code %v, expected %v, stderr: %v\n%v", code, exitOK, stderr.String(), stdout.String())
+	}
+}
+
+func TestRunValidateSARIF(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := run([]string{"validate", "-format", "sarif", "../../testdata/providers/root"}, &stdout, &stderr)
+	if code != exitFindings {
+		t.Fatalf("Unexpected exit code %v, expected %v, stderr: %v", code, exitFindings, stderr.String())
+	}
+	for _, s := range []string{`"version": "2.1.0"`, `"ruleId": "provider-wiring"`, `"uri": "main.tf"`} {
+		if !strings.Contains(stdout.String(), s) {
+			t.Fatalf("SARIF output does not contain %#q:\n%v", s, stdout.String())
+		}
+	}
+}
+
+func TestRunInputs(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := run([]string{"inputs", "-var-file", "../../testdata/inputs/vars/prod.tfvars", "../../testdata/inputs/root"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Unexpected exit code %v, stderr: %v", code, stderr.String())
+	}
+	// -var-file takes precedence over terraform.tfvars
+	if s := `vpc.name will be "Production VPC"`; !strings.Contains(stdout.String(), s) {
+		t.Fatalf("Output does not contain %#q:\n%v", s, stdout.String())
+	}
+}
@@ -0,0 +1,360 @@
+package tfparser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// TokenKind is a kind of token of concrete syntax tree
+type TokenKind int
+
+const (
+	TokenWhitespace TokenKind = iota // spaces and tabs
+	TokenNewline                     // "\n" or "\r\n"
+	TokenComment                     // single line comment without newline, or multiline comment
+	TokenIdentifier                  // identifier, keyword or number
+	TokenString                      // quoted string including quotes and interpolations
+	TokenHeredoc                     // heredoc from '<<' till the closing marker
+	TokenSymbol                      // punctuation or operator
+)
+
+// Token is a piece of source file. Concatenated tokens of a file give the file back
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  Pos
+}
+
+// isTrivia tells if token has no meaning for the configuration
+func (t *Token) isTrivia() bool {
+	return t.Kind == TokenWhitespace || t.Kind == TokenNewline || t.Kind == TokenComment
+}
+
+// CSTKind is a kind of node of concrete syntax tree
+type CSTKind int
+
+const (
+	CSTToken      CSTKind = iota // leaf node with a single token
+	CSTBody                      // attributes, blocks and trivia between them
+	CSTBlock                     // type, labels, '{', body and '}'
+	CSTAttribute                 // name, '=' and expression, without trailing comment and newline
+	CSTExpression                // value of an attribute
+)
+
+// CSTNode is a node of concrete syntax tree. Unlike TFconfig it keeps every token of the source,
+// including comments and whitespace, so the file can be printed back byte-for-byte
+type CSTNode struct {
+	Kind     CSTKind
+	Token    *Token // CSTToken only
+	Children []*CSTNode
+}
+
+// CSTFile is a concrete syntax tree of a terraform file
+type CSTFile struct {
+	Filename string
+	Body     *CSTNode
+}
+
+// ParseCST parses src into concrete syntax tree. filename is used in token positions only
+func ParseCST(src []byte, filename string) (*CSTFile, error) {
+	tokens, err := newParser(string(src), filename, &TFconfig{}).popTokens()
+	if err != nil {
+		return nil, err
+	}
+	cp := &cstParser{tokens: tokens}
+	body, err := cp.parseBody(false)
+	if err != nil {
+		return nil, err
+	}
+	return &CSTFile{filename, body}, nil
+}
+
+// ParseCSTFile reads filename and parses it into concrete syntax tree
+func ParseCSTFile(filename string) (*CSTFile, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCST(data, filename)
+}
+
+// Bytes prints the file back
+func (f *CSTFile) Bytes() []byte {
+	return []byte(f.Body.String())
+}
+
+// Blocks returns top level blocks of the file of given type, all of them if blockType is empty
+func (f *CSTFile) Blocks(blockType string) []*CSTNode {
+	return f.Body.Blocks(blockType)
+}
+
+// String returns source text of the node
+func (n *CSTNode) String() string {
+	var b strings.Builder
+	for _, t := range n.Tokens() {
+		b.WriteString(t.Text)
+	}
+	return b.String()
+}
+
+// Tokens returns all tokens of the node in source order
+func (n *CSTNode) Tokens() []*Token {
+	if n.Kind == CSTToken {
+		return []*Token{n.Token}
+	}
+	var tokens []*Token
+	for _, c := range n.Children {
+		tokens = append(tokens, c.Tokens()...)
+	}
+	return tokens
+}
+
+// Pos returns position of the first token of the node
+func (n *CSTNode) Pos() Pos {
+	if tokens := n.Tokens(); len(tokens) > 0 {
+		return tokens[0].Pos
+	}
+	return Pos{}
+}
+
+// Body returns body of a block, or the node itself if it is a body
+func (n *CSTNode) Body() *CSTNode {
+	if n.Kind == CSTBody {
+		return n
+	}
+	for _, c := range n.Children {
+		if c.Kind == CSTBody {
+			return c
+		}
+	}
+	return nil
+}
+
+// Blocks returns nested blocks of a body or a block of given type, all of them if blockType is empty
+func (n *CSTNode) Blocks(blockType string) []*CSTNode {
+	var blocks []*CSTNode
+	if body := n.Body(); body != nil {
+		for _, c := range body.Children {
+			if c.Kind == CSTBlock && (blockType == "" || c.Type() == blockType) {
+				blocks = append(blocks, c)
+			}
+		}
+	}
+	return blocks
+}
+
+// Block returns nested block with given type and labels, nil if there is no such block
+func (n *CSTNode) Block(blockType string, labels ...string) *CSTNode {
+	for _, b := range n.Blocks(blockType) {
+		if strings.Join(b.Labels(), "\x00") == strings.Join(labels, "\x00") {
+			return b
+		}
+	}
+	return nil
+}
+
+// Attributes returns attributes of a body or a block
+func (n *CSTNode) Attributes() []*CSTNode {
+	var attrs []*CSTNode
+	if body := n.Body(); body != nil {
+		for _, c := range body.Children {
+			if c.Kind == CSTAttribute {
+				attrs = append(attrs, c)
+			}
+		}
+	}
+	return attrs
+}
+
+// Attribute returns attribute of a body or a block with given name, nil if there is no such attribute
+func (n *CSTNode) Attribute(name string) *CSTNode {
+	for _, a := range n.Attributes() {
+		if a.Name() == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Type returns type of a block, e.g. 'module'
+func (n *CSTNode) Type() string {
+	if n.Kind != CSTBlock {
+		return ""
+	}
+	return n.Children[0].Token.Text
+}
+
+// Labels returns unquoted labels of a block
+func (n *CSTNode) Labels() []string {
+	var labels []string
+	if n.Kind != CSTBlock {
+		return nil
+	}
+	for _, c := range n.Children[1:] {
+		if c.Kind != CSTToken || c.Token.Kind == TokenSymbol {
+			break
+		}
+		if !c.Token.isTrivia() {
+			labels = append(labels, unquote(c.Token.Text))
+		}
+	}
+	return labels
+}
+
+// Name returns name of an attribute
+func (n *CSTNode) Name() string {
+	if n.Kind != CSTAttribute {
+		return ""
+	}
+	return unquote(n.Children[0].Token.Text)
+}
+
+// Value returns expression of an attribute
+func (n *CSTNode) Value() *CSTNode {
+	for _, c := range n.Children {
+		if c.Kind == CSTExpression {
+			return c
+		}
+	}
+	return nil
+}
+
+func tokenNode(t *Token) *CSTNode {
+	return &CSTNode{Kind: CSTToken, Token: t}
+}
+
+type cstParser struct {
+	tokens []*Token
+	i      int
+}
+
+func (cp *cstParser) peek() *Token {
+	if cp.i >= len(cp.tokens) {
+		return nil
+	}
+	return cp.tokens[cp.i]
+}
+
+// peekSignificant returns the first token after whitespace, on the same line
+func (cp *cstParser) peekSignificant() *Token {
+	for i := cp.i; i < len(cp.tokens); i++ {
+		if cp.tokens[i].Kind != TokenWhitespace {
+			return cp.tokens[i]
+		}
+	}
+	return nil
+}
+
+func (cp *cstParser) next() *CSTNode {
+	cp.i++
+	return tokenNode(cp.tokens[cp.i-1])
+}
+
+// parseBody parses attributes and blocks till the end of file or, in a block, till closing brace
+func (cp *cstParser) parseBody(block bool) (*CSTNode, error) {
+	body := &CSTNode{Kind: CSTBody}
+	for {
+		t := cp.peek()
+		switch {
+		case t == nil:
+			if block {
+				return nil, fmt.Errorf("Unable to find closing brace for block")
+			}
+			return body, nil
+		case t.isTrivia():
+			body.Children = append(body.Children, cp.next())
+		case t.Kind == TokenSymbol && t.Text == "}" && block:
+			return body, nil
+		case t.Kind == TokenIdentifier || t.Kind == TokenString:
+			cp.i++
+			next := cp.peekSignificant()
+			cp.i--
+			var n *CSTNode
+			var err error
+			if next != nil && next.Kind == TokenSymbol && next.Text == "=" {
+				n, err = cp.parseAttribute()
+			} else {
+				n, err = cp.parseBlock()
+			}
+			if err != nil {
+				return nil, err
+			}
+			body.Children = append(body.Children, n)
+		default:
+			return nil, fmt.Errorf("%v: Unexpected %#q", t.Pos, t.Text)
+		}
+	}
+}
+
+// parseAttribute parses 'name = expression'. Expression ends with newline or comment outside
+// of brackets, or with closing brace of a single line block
+func (cp *cstParser) parseAttribute() (*CSTNode, error) {
+	attr := &CSTNode{Kind: CSTAttribute}
+	for {
+		n := cp.next()
+		attr.Children = append(attr.Children, n)
+		if n.Token.Text == "=" {
+			break
+		}
+	}
+	for t := cp.peek(); t != nil && t.Kind == TokenWhitespace; t = cp.peek() {
+		attr.Children = append(attr.Children, cp.next())
+	}
+	expr := &CSTNode{Kind: CSTExpression}
+	depth := 0
+	for t := cp.peek(); t != nil; t = cp.peek() {
+		if depth == 0 && (t.Kind == TokenNewline || t.Kind == TokenComment && !strings.HasPrefix(t.Text, "/*")) {
+			break
+		}
+		if t.Kind == TokenSymbol {
+			switch t.Text {
+			case "{", "[", "(":
+				depth++
+			case "}", "]", ")":
+				depth--
+			}
+		}
+		if depth < 0 {
+			break
+		}
+		expr.Children = append(expr.Children, cp.next())
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("%v: Unable to find closing bracket in value of %#q", attr.Pos(), attr.Name())
+	}
+	// trailing whitespace belongs to the body
+	for len(expr.Children) > 0 && expr.Children[len(expr.Children)-1].Token.isTrivia() {
+		expr.Children = expr.Children[:len(expr.Children)-1]
+		cp.i--
+	}
+	if len(expr.Children) == 0 {
+		return nil, fmt.Errorf("%v: Value expected for %#q", attr.Pos(), attr.Name())
+	}
+	attr.Children = append(attr.Children, expr)
+	return attr, nil
+}
+
+// parseBlock parses 'type "label" ... { body }'
+func (cp *cstParser) parseBlock() (*CSTNode, error) {
+	block := &CSTNode{Kind: CSTBlock}
+	for {
+		t := cp.peek()
+		switch {
+		case t == nil:
+			return nil, fmt.Errorf("%v: Unable to find opening brace of block", block.Pos())
+		case t.Kind == TokenSymbol && t.Text == "{":
+			block.Children = append(block.Children, cp.next())
+			body, err := cp.parseBody(true)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", block.Pos(), err)
+			}
+			block.Children = append(block.Children, body, cp.next())
+			return block, nil
+		case t.Kind == TokenIdentifier || t.Kind == TokenString || t.Kind == TokenWhitespace:
+			block.Children = append(block.Children, cp.next())
+		default:
+			return nil, fmt.Errorf("%v: Unexpected %#q in block header", t.Pos, t.Text)
+		}
+	}
+}
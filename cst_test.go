@@ -0,0 +1,128 @@
+package tfparser
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCSTRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("testdata/*/*.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	more, _ := filepath.Glob("testdata/*/*/*.tf")
+	files = append(files, more...)
+	if len(files) == 0 {
+		t.Fatal("No testdata files found")
+	}
+	for _, filename := range files {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := ParseCST(data, filename)
+		if err != nil {
+			t.Errorf("ParseCST returned an error for %v, %v", filename, err)
+			continue
+		}
+		if string(f.Bytes()) != string(data) {
+			t.Errorf("File %v was not printed back as is:\n%s", filename, f.Bytes())
+		}
+	}
+}
+
+var cstTestTFCode = "# header\r\n" + `module "vpc" {
+  source = "../../modules/vpc" # pinned
+  tags   = {
+    Name = "main" // name
+  }
+  user_data = <<EOT
+  text
+EOT
+}
+
+variable "env" { default = "dev" }
+`
+
+func TestCSTStructure(t *testing.T) {
+	f, err := ParseCST([]byte(cstTestTFCode), "main.tf")
+	if err != nil {
+		t.Fatalf("ParseCST returned an error, %v", err)
+	}
+	if string(f.Bytes()) != cstTestTFCode {
+		t.Fatalf("File was not printed back as is:\n%s", f.Bytes())
+	}
+	if blocks := f.Blocks(""); len(blocks) != 2 {
+		t.Fatalf("Unexpected number of blocks %v, expected 2", len(blocks))
+	}
+	m := f.Body.Block("module", "vpc")
+	if m == nil {
+		t.Fatal("Block 'module \"vpc\"' was not found")
+	}
+	if pos := m.Pos(); pos != (Pos{"main.tf", 2, 1}) {
+		t.Errorf("Unexpected block position %v", pos)
+	}
+	var names []string
+	for _, a := range m.Attributes() {
+		names = append(names, a.Name())
+	}
+	if expected := []string{"source", "tags", "user_data"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Unexpected attributes %v, expected %v", names, expected)
+	}
+	if v := m.Attribute("source").Value().String(); v != `"../../modules/vpc"` {
+		t.Errorf("Unexpected value of source %#q", v)
+	}
+	if v := m.Attribute("tags").Value().String(); v != "{\n    Name = \"main\" // name\n  }" {
+		t.Errorf("Unexpected value of tags %#q", v)
+	}
+	if v := m.Attribute("user_data").Value().String(); v != "<<EOT\n  text\nEOT" {
+		t.Errorf("Unexpected value of user_data %#q", v)
+	}
+	v := f.Body.Block("variable", "env")
+	if v == nil || v.Attribute("default") == nil || v.Attribute("default").Value().String() != `"dev"` {
+		t.Errorf("Single line block was not parsed")
+	}
+
+	comments := 0
+	for _, tok := range f.Body.Tokens() {
+		if tok.Kind == TokenComment {
+			comments++
+		}
+	}
+	if comments != 3 {
+		t.Errorf("Unexpected number of comments %v, expected 3", comments)
+	}
+}
+
+func TestCSTErrors(t *testing.T) {
+	for _, src := range []string{
+		"module \"x\" {\n",
+		"a = [1,\n",
+		"a = \n",
+		"/* unterminated",
+		"= 1\n",
+		"a = \"b\\\"\n",
+		"/* outer /* nested */ still comment\na = 1\n",
+	} {
+		if _, err := ParseCST([]byte(src), ""); err == nil {
+			t.Errorf("ParseCST did not return an error for %#q", src)
+		}
+	}
+}
+
+func TestCSTNestedComment(t *testing.T) {
+	src := "/* outer /* nested */ still comment */\na = \"b\\\\\"\n"
+	f, err := ParseCST([]byte(src), "")
+	if err != nil {
+		t.Fatalf("ParseCST returned an error, %v", err)
+	}
+	if string(f.Bytes()) != src {
+		t.Fatalf("File was not printed back as is:\n%s", f.Bytes())
+	}
+	tokens := f.Body.Tokens()
+	if tokens[0].Kind != TokenComment || tokens[0].Text != "/* outer /* nested */ still comment */" {
+		t.Fatalf("Unexpected first token %#v, expected the whole nested comment", tokens[0])
+	}
+}
@@ -0,0 +1,145 @@
+package tfparser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// diagram is a format independent representation of module calls of a configuration
+type diagram struct {
+	modules   []string          // module call names, sorted
+	sources   map[string]string // module call name -> source path
+	providers []string          // addresses of provider configurations, sorted
+	links     []providerLink    // module call -> provider configuration, sorted
+	flows     [][2]string       // module call -> module call consuming its outputs, sorted
+}
+
+type providerLink struct {
+	module   string
+	alias    string
+	provider string
+}
+
+func newDiagram(config *TFconfig) *diagram {
+	d := &diagram{modules: sortedModuleNames(config), sources: make(map[string]string)}
+	providers := make(map[string]bool)
+	for addr := range config.Providers {
+		providers[addr] = true
+	}
+	for _, name := range d.modules {
+		m := config.Modules[name]
+		d.sources[name] = m.SourcePath
+		aliases := make([]string, 0, len(m.Providers))
+		for alias := range m.Providers {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+		for _, alias := range aliases {
+			// providers passed into modules are shown even if they are not declared in config
+			providers[m.Providers[alias]] = true
+			d.links = append(d.links, providerLink{name, alias, m.Providers[alias]})
+		}
+	}
+	d.providers = sortedKeys(providers)
+
+	g := NewGraph(config)
+	for _, name := range d.modules {
+		for _, from := range g.moduleDependencies("module." + name) {
+			d.flows = append(d.flows, [2]string{strings.TrimPrefix(from, "module."), name})
+		}
+	}
+	sort.Slice(d.flows, func(i, j int) bool {
+		if d.flows[i][0] != d.flows[j][0] {
+			return d.flows[i][0] < d.flows[j][0]
+		}
+		return d.flows[i][1] < d.flows[j][1]
+	})
+	return d
+}
+
+// moduleDependencies returns module calls address depends on, either directly or
+// through other objects like locals, which are not module calls. Result is sorted
+func (g *Graph) moduleDependencies(address string) []string {
+	modules := make(map[string]bool)
+	visited := map[string]bool{address: true}
+	queue := g.Dependencies(address)
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if visited[cur] {
+			continue
+		}
+		visited[cur] = true
+		if strings.HasPrefix(cur, "module.") {
+			modules[cur] = true
+			continue
+		}
+		queue = append(queue, g.Dependencies(cur)...)
+	}
+	return sortedKeys(modules)
+}
+
+// WriteDOT writes Graphviz DOT diagram of module calls of config to w. Module calls are linked
+// to provider configurations passed into them, labelled with provider alias. Dashed edges show
+// data flow between module calls, from module call to module calls which consume its outputs.
+// Only module calls of config itself are drawn, module calls nested in called modules are not
+func WriteDOT(w io.Writer, config *TFconfig) error {
+	d := newDiagram(config)
+	var b strings.Builder
+	b.WriteString("digraph tfconfig {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, name := range d.modules {
+		fmt.Fprintf(&b, "  %v [label=%v];\n", dotQuote("module."+name), dotQuote(name+"\n"+d.sources[name]))
+	}
+	for _, addr := range d.providers {
+		fmt.Fprintf(&b, "  %v [label=%v, shape=ellipse];\n", dotQuote("provider."+addr), dotQuote(addr))
+	}
+	for _, l := range d.links {
+		fmt.Fprintf(&b, "  %v -> %v [label=%v];\n", dotQuote("module."+l.module), dotQuote("provider."+l.provider), dotQuote(l.alias))
+	}
+	for _, f := range d.flows {
+		fmt.Fprintf(&b, "  %v -> %v [style=dashed];\n", dotQuote("module."+f[0]), dotQuote("module."+f[1]))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes Mermaid flowchart of module calls of config to w.
+// Contents of the diagram is the same as for WriteDOT. Nodes are numbered, as names of module
+// calls and provider addresses may contain characters which are not allowed in Mermaid node ids
+func WriteMermaid(w io.Writer, config *TFconfig) error {
+	d := newDiagram(config)
+	ids := make(map[string]string)
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, name := range d.modules {
+		ids["module."+name] = fmt.Sprintf("module%d", i)
+		fmt.Fprintf(&b, "  %v[%v]\n", ids["module."+name], mermaidQuote(name+"<br/>"+d.sources[name]))
+	}
+	for i, addr := range d.providers {
+		ids["provider."+addr] = fmt.Sprintf("provider%d", i)
+		fmt.Fprintf(&b, "  %v([%v])\n", ids["provider."+addr], mermaidQuote(addr))
+	}
+	for _, l := range d.links {
+		fmt.Fprintf(&b, "  %v -- %v --> %v\n", ids["module."+l.module], mermaidQuote(l.alias), ids["provider."+l.provider])
+	}
+	for _, f := range d.flows {
+		fmt.Fprintf(&b, "  %v -.-> %v\n", ids["module."+f[0]], ids["module."+f[1]])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,113 @@
+package tfparser
+
+import (
+	"strings"
+	"testing"
+)
+
+var diagramTestTFCode = `
+provider "aws" {
+  alias  = "us-east-1"
+  region = "us-east-1"
+}
+
+module "vpc" {
+  source = "../../modules/vpc"
+
+  providers = {
+    aws = aws.us-east-1
+  }
+}
+
+locals {
+  vpc_id = module.vpc.vpc_id
+}
+
+module "routing" {
+  source = "../../modules/vpc-routing"
+  vpc_id = local.vpc_id
+
+  providers = {
+    aws.alice = aws.us-east-1
+    aws.bob   = aws.ap-southeast-2
+  }
+}
+`
+
+func TestWriteDOT(t *testing.T) {
+	config, err := ParseString(diagramTestTFCode)
+	if err != nil {
+		t.Fatalf("ParseString returned an error, %v", err)
+	}
+	var b strings.Builder
+	if err := WriteDOT(&b, config); err != nil {
+		t.Fatalf("WriteDOT returned an error, %v", err)
+	}
+	expected := `digraph tfconfig {
+  rankdir=LR;
+  node [shape=box];
+  "module.routing" [label="routing\n../../modules/vpc-routing"];
+  "module.vpc" [label="vpc\n../../modules/vpc"];
+  "provider.aws.ap-southeast-2" [label="aws.ap-southeast-2", shape=ellipse];
+  "provider.aws.us-east-1" [label="aws.us-east-1", shape=ellipse];
+  "module.routing" -> "provider.aws.us-east-1" [label="aws.alice"];
+  "module.routing" -> "provider.aws.ap-southeast-2" [label="aws.bob"];
+  "module.vpc" -> "provider.aws.us-east-1" [label="aws"];
+  "module.vpc" -> "module.routing" [style=dashed];
+}
+`
+	if b.String() != expected {
+		t.Fatalf("Unexpected DOT diagram:\n%v\nexpected:\n%v", b.String(), expected)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	config, err := ParseString(diagramTestTFCode)
+	if err != nil {
+		t.Fatalf("ParseString returned an error, %v", err)
+	}
+	var b strings.Builder
+	if err := WriteMermaid(&b, config); err != nil {
+		t.Fatalf("WriteMermaid returned an error, %v", err)
+	}
+	expected := `flowchart LR
+  module0["routing<br/>../../modules/vpc-routing"]
+  module1["vpc<br/>../../modules/vpc"]
+  provider0(["aws.ap-southeast-2"])
+  provider1(["aws.us-east-1"])
+  module0 -- "aws.alice" --> provider1
+  module0 -- "aws.bob" --> provider0
+  module1 -- "aws" --> provider1
+  module1 -.-> module0
+`
+	if b.String() != expected {
+		t.Fatalf("Unexpected Mermaid diagram:\n%v\nexpected:\n%v", b.String(), expected)
+	}
+}
+
+func TestWriteMermaidDistinctIDs(t *testing.T) {
+	config, err := ParseString(`
+module "x" {
+  source = "./x"
+  providers = {
+    aws.a = aws.us-east-1
+    aws.b = aws.us_east_1
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseString returned an error, %v", err)
+	}
+	var b strings.Builder
+	if err := WriteMermaid(&b, config); err != nil {
+		t.Fatalf("WriteMermaid returned an error, %v", err)
+	}
+	for _, line := range []string{
+		`  module0 -- "aws.a" --> provider0`,
+		`  module0 -- "aws.b" --> provider1`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("Line %#q not found in Mermaid diagram:\n%v", line, b.String())
+		}
+	}
+}
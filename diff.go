@@ -0,0 +1,141 @@
+package tfparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeKind is a kind of change found by Diff
+type ChangeKind string
+
+// Kinds of changes found by Diff
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Fields of module calls compared by Diff
+const (
+	FieldSource    = "source"
+	FieldParameter = "parameter"
+	FieldProvider  = "provider"
+)
+
+// ConfigChange is a semantic difference of module calls found by Diff
+type ConfigChange struct {
+	Kind   ChangeKind `json:"kind"`
+	Module string     `json:"module"`           // name of module call
+	Field  string     `json:"field,omitempty"`  // empty for module call itself, FieldSource, FieldParameter or FieldProvider
+	Name   string     `json:"name,omitempty"`   // name of parameter or provider alias
+	Before string     `json:"before,omitempty"` // value in the old configuration
+	After  string     `json:"after,omitempty"`  // value in the new configuration
+	Pos    Pos        `json:"pos"`              // position in the new configuration, or in the old one for removals
+}
+
+func (c ConfigChange) String() string {
+	var what string
+	switch c.Field {
+	case "":
+		return fmt.Sprintf("%v: module %#q %v", c.Pos, c.Module, c.Kind)
+	case FieldSource:
+		what = "source"
+	default:
+		what = fmt.Sprintf("%v %#q", c.Field, c.Name)
+	}
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%v: module %#q: %v added as %#q", c.Pos, c.Module, what, c.After)
+	case ChangeRemoved:
+		return fmt.Sprintf("%v: module %#q: %v %#q removed", c.Pos, c.Module, what, c.Before)
+	}
+	return fmt.Sprintf("%v: module %#q: %v changed from %#q to %#q", c.Pos, c.Module, what, c.Before, c.After)
+}
+
+// Diff compares module calls of configurations a and b and returns changes from a to b: added and
+// removed module calls, changed sources, added, changed and removed parameters and provider mappings.
+// Formatting, comments and order of attributes are ignored. Changes are sorted by module name, then
+// source, parameters and providers, each sorted by name
+func Diff(a, b *TFconfig) []ConfigChange {
+	var changes []ConfigChange
+	names := make(map[string]string)
+	for name := range a.Modules {
+		names[name] = ""
+	}
+	for name := range b.Modules {
+		names[name] = ""
+	}
+	for _, name := range sortedStringKeys(names) {
+		before, after := a.Modules[name], b.Modules[name]
+		switch {
+		case before == nil:
+			changes = append(changes, ConfigChange{Kind: ChangeAdded, Module: name, After: after.SourcePath, Pos: after.Pos})
+		case after == nil:
+			changes = append(changes, ConfigChange{Kind: ChangeRemoved, Module: name, Before: before.SourcePath, Pos: before.Pos})
+		default:
+			changes = append(changes, diffModules(name, before, after)...)
+		}
+	}
+	return changes
+}
+
+// diffModules compares calls of module name
+func diffModules(name string, before, after *Module) []ConfigChange {
+	var changes []ConfigChange
+	if before.SourcePath != after.SourcePath {
+		changes = append(changes, ConfigChange{ChangeChanged, name, FieldSource, "", before.SourcePath, after.SourcePath, after.Pos})
+	}
+	changes = append(changes, diffValues(name, FieldParameter, before.Parameters, after.Parameters, before.expr, after.expr, before.ParametersPos, after.ParametersPos)...)
+	providerExpr := func(providers map[string]string) func(string) string {
+		return func(key string) string { return providers[key] }
+	}
+	changes = append(changes, diffValues(name, FieldProvider, before.Providers, after.Providers, providerExpr(before.Providers), providerExpr(after.Providers), before.ProvidersPos, after.ProvidersPos)...)
+	return changes
+}
+
+// diffValues compares parameters or provider mappings of module call as they are written, which
+// beforeExpr and afterExpr return, so e.g. "1" and 1 differ. Values are reported, or expressions if
+// values are the same
+func diffValues(module, field string, before, after map[string]string, beforeExpr, afterExpr func(string) string, beforePos, afterPos map[string]Pos) []ConfigChange {
+	var changes []ConfigChange
+	keys := make(map[string]string)
+	for key := range before {
+		keys[key] = ""
+	}
+	for key := range after {
+		keys[key] = ""
+	}
+	for _, key := range sortedStringKeys(keys) {
+		b, inBefore := before[key]
+		a, inAfter := after[key]
+		switch {
+		case !inBefore:
+			changes = append(changes, ConfigChange{ChangeAdded, module, field, key, "", a, afterPos[key]})
+		case !inAfter:
+			changes = append(changes, ConfigChange{ChangeRemoved, module, field, key, b, "", beforePos[key]})
+		case normalizeExpression(afterExpr(key)) != normalizeExpression(beforeExpr(key)):
+			if a == b {
+				b, a = beforeExpr(key), afterExpr(key)
+			}
+			changes = append(changes, ConfigChange{ChangeChanged, module, field, key, b, a, afterPos[key]})
+		}
+	}
+	return changes
+}
+
+// normalizeExpression returns expr without formatting and comments, so expressions which differ
+// only in them are equal
+func normalizeExpression(expr string) string {
+	tokens, err := newParser(expr, "", &TFconfig{}).popTokens()
+	if err != nil {
+		return expr
+	}
+	var parts []string
+	for _, t := range tokens {
+		// commas are optional separators of multi-line objects and trailing commas of lists
+		if !t.isTrivia() && t.Text != "," {
+			parts = append(parts, t.Text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
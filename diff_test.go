@@ -0,0 +1,85 @@
+package tfparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+var diffTestBefore = `module "vpc" {
+  source = "./modules/vpc"
+  cidr   = "10.0.0.0/16"
+  tags   = { Name = "vpc", Env = "prod" }
+  azs    = ["a", "b"]
+  name   = "main"
+  port   = "1"
+  public = true
+
+  providers = {
+    aws = aws.west
+  }
+}
+
+module "old" {
+  source = "./modules/old"
+}
+`
+
+var diffTestAfter = `# reformatted, reordered and changed
+module "app" {
+  source = "./modules/app"
+}
+
+module "vpc" {
+  azs = [
+    "a",
+    "b",
+  ]
+  tags = {
+    Name = "vpc" # comment
+    Env  = "prod"
+  }
+  source = "./modules/vpc-v2"
+  cidr   = "10.1.0.0/16"
+  nat    = true
+  port   = 1
+  public = "true"
+
+  providers = {
+    aws     = aws.east
+    aws.dns = aws.west
+  }
+}
+`
+
+func TestDiff(t *testing.T) {
+	a, err := newParser(diffTestBefore, "before.tf", &TFconfig{}).parse()
+	if err != nil {
+		t.Fatalf("parse returned an error, %v", err)
+	}
+	b, err := newParser(diffTestAfter, "after.tf", &TFconfig{}).parse()
+	if err != nil {
+		t.Fatalf("parse returned an error, %v", err)
+	}
+	var got []string
+	for _, c := range Diff(a, b) {
+		got = append(got, c.String())
+	}
+	expected := []string{
+		"after.tf:2:1: module `app` added",
+		"before.tf:15:1: module `old` removed",
+		"after.tf:6:1: module `vpc`: source changed from `./modules/vpc` to `./modules/vpc-v2`",
+		"after.tf:16:3: module `vpc`: parameter `cidr` changed from `10.0.0.0/16` to `10.1.0.0/16`",
+		"before.tf:6:3: module `vpc`: parameter `name` `main` removed",
+		"after.tf:17:3: module `vpc`: parameter `nat` added as `true`",
+		"after.tf:18:3: module `vpc`: parameter `port` changed from `\"1\"` to `1`",
+		"after.tf:19:3: module `vpc`: parameter `public` changed from `true` to `\"true\"`",
+		"after.tf:22:5: module `vpc`: provider `aws` changed from `aws.west` to `aws.east`",
+		"after.tf:23:5: module `vpc`: provider `aws.dns` added as `aws.west`",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected changes:\n%v\nexpected:\n%v", got, expected)
+	}
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Errorf("Diff of the same configuration returned %v", changes)
+	}
+}
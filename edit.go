@@ -0,0 +1,522 @@
+package tfparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Editing methods of CSTFile change only the lines they have to: the edited attribute and, when
+// the longest name of an aligned group changes, the padding before '=' of the group. Positions of
+// tokens refer to the source the file was parsed from, they are not updated by edits
+
+// SetSource sets source of module call
+func (f *CSTFile) SetSource(module, source string) error {
+	return f.SetParameter(module, "source", strconv.Quote(source))
+}
+
+// SetParameter sets parameter of module call to value, which is an expression, e.g. '"vpc"'
+// or 'var.name'. New parameter is added after the last one, before meta-arguments
+func (f *CSTFile) SetParameter(module, name, value string) error {
+	m, err := f.module(module)
+	if err != nil {
+		return err
+	}
+	expr, err := parseCSTValue(value)
+	if err != nil {
+		return err
+	}
+	if a := m.Attribute(name); a != nil {
+		a.Children[len(a.Children)-1] = expr
+		alignBody(m.Body(), a)
+		return nil
+	}
+	if !isCSTIdentifier(name) {
+		return fmt.Errorf("Invalid parameter name %#q", name)
+	}
+	var anchor *CSTNode
+	for _, a := range m.Attributes() {
+		if !isMetaArgument(a.Name()) {
+			anchor = a
+		}
+	}
+	a := newCSTAttribute(name, expr)
+	insertAttribute(m, anchor, a)
+	alignBody(m.Body(), a)
+	return nil
+}
+
+// RemoveParameter removes parameter of module call along with its line
+func (f *CSTFile) RemoveParameter(module, name string) error {
+	m, err := f.module(module)
+	if err != nil {
+		return err
+	}
+	a := m.Attribute(name)
+	if a == nil {
+		return fmt.Errorf("Parameter %#q is not found in module %#q", name, module)
+	}
+	body := m.Body()
+	entries := bodyEntries(body)
+	k := 0
+	for k < len(entries) && entries[k].owner != a {
+		k++
+	}
+	for i, c := range body.Children {
+		if c == a {
+			body.Children = removeLine(body.Children, i, i+1)
+			break
+		}
+	}
+	alignNeighbours(bodyEntries(body), k)
+	return nil
+}
+
+// SetProviderMapping passes provider configuration into module call as alias,
+// e.g. 'aws.alice = aws.us-east-1'. providers argument is added if there is none
+func (f *CSTFile) SetProviderMapping(module, alias, provider string) error {
+	m, err := f.module(module)
+	if err != nil {
+		return err
+	}
+	key, err := lexCSTKey(alias)
+	if err != nil {
+		return err
+	}
+	value, err := parseCSTValue(provider)
+	if err != nil {
+		return err
+	}
+	a := m.Attribute("providers")
+	if a == nil {
+		indent := attributeIndent(m)
+		expr, err := parseCSTValue(fmt.Sprintf("{\n%v  %v = %v\n%v}", indent, alias, provider, indent))
+		if err != nil {
+			return err
+		}
+		var anchor *CSTNode
+		if attrs := m.Attributes(); len(attrs) > 0 {
+			anchor = attrs[len(attrs)-1]
+		}
+		a = newCSTAttribute("providers", expr)
+		insertAttribute(m, anchor, a)
+		if anchor != nil {
+			// providers are separated from parameters with a blank line
+			body := m.Body()
+			for i, c := range body.Children {
+				if c == a {
+					body.Children = append(body.Children[:i-1], append([]*CSTNode{newlineNode()}, body.Children[i-1:]...)...)
+					break
+				}
+			}
+		}
+		return nil
+	}
+
+	expr := a.Value()
+	entries, err := objectEntries(expr)
+	if err != nil {
+		return fmt.Errorf("Invalid providers of module %#q: %v", module, err)
+	}
+	old := append([]*CSTNode{}, expr.Children...)
+	for k, e := range entries {
+		if e.key == alias {
+			children := append([]*CSTNode{}, expr.Children[:e.valueStart]...)
+			children = append(children, value.Children...)
+			expr.Children = append(children, expr.Children[e.valueEnd:]...)
+			return alignObject(module, expr, old, k)
+		}
+	}
+
+	entry := append(key, wsNode(" "), tokenNode(&Token{Kind: TokenSymbol, Text: "="}), wsNode(" "))
+	entry = append(entry, value.Children...)
+	var insert []*CSTNode
+	at := len(expr.Children) - 1 // before closing brace
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		indent := ""
+		if ws := expr.Children[last.start-1]; isTokenKind(ws, TokenWhitespace) {
+			indent = ws.Token.Text
+		}
+		at = indexOfKind(expr.Children, last.valueEnd, TokenNewline)
+		if at < 0 {
+			// single line object
+			at = last.valueEnd
+			insert = append([]*CSTNode{tokenNode(&Token{Kind: TokenSymbol, Text: ","}), wsNode(" ")}, entry...)
+		} else {
+			at++
+			insert = append(append([]*CSTNode{wsNode(indent)}, entry...), newlineNode())
+		}
+	} else {
+		indent := attributeIndent(m)
+		insert = append(append([]*CSTNode{newlineNode(), wsNode(indent + "  ")}, entry...), newlineNode(), wsNode(indent))
+		at = 1
+		for at < len(expr.Children)-1 && isTrivia(expr.Children[at]) {
+			at++
+		}
+		expr.Children = append(expr.Children[:1], expr.Children[at:]...)
+		at = 1
+	}
+	expr.Children = append(expr.Children[:at], append(insert, expr.Children[at:]...)...)
+	return alignObject(module, expr, old, len(entries))
+}
+
+// RemoveProviderMapping removes alias from providers passed into module call
+func (f *CSTFile) RemoveProviderMapping(module, alias string) error {
+	m, err := f.module(module)
+	if err != nil {
+		return err
+	}
+	a := m.Attribute("providers")
+	if a == nil {
+		return fmt.Errorf("Module %#q has no providers", module)
+	}
+	expr := a.Value()
+	entries, err := objectEntries(expr)
+	if err != nil {
+		return fmt.Errorf("Invalid providers of module %#q: %v", module, err)
+	}
+	old := append([]*CSTNode{}, expr.Children...)
+	for k, e := range entries {
+		if e.key == alias {
+			expr.Children = removeLine(expr.Children, e.start, e.valueEnd)
+			return alignObject(module, expr, old, k)
+		}
+	}
+	return fmt.Errorf("Provider %#q is not passed into module %#q", alias, module)
+}
+
+func (f *CSTFile) module(name string) (*CSTNode, error) {
+	m := f.Body.Block("module", name)
+	if m == nil {
+		return nil, fmt.Errorf("Module %#q is not found", name)
+	}
+	return m, nil
+}
+
+// isMetaArgument tells if module argument is not a parameter of the module
+func isMetaArgument(name string) bool {
+	switch name {
+	case "providers", "depends_on", "count", "for_each":
+		return true
+	}
+	return false
+}
+
+// cstEntry is 'key = value' of a body or an object expression
+type cstEntry struct {
+	owner      *CSTNode // attribute of a body, or object expression
+	key        string
+	start, eq  int // indexes in owner children of the first key token and of '='
+	valueStart int // object expression only: value is children[valueStart:valueEnd]
+	valueEnd   int
+	line       int // line number relative to the body or expression
+}
+
+// bodyEntries returns attributes of body
+func bodyEntries(body *CSTNode) []cstEntry {
+	var entries []cstEntry
+	line := 0
+	for _, c := range body.Children {
+		if c.Kind == CSTAttribute {
+			eq := 1
+			for c.Children[eq].Token.Text != "=" {
+				eq++
+			}
+			entries = append(entries, cstEntry{owner: c, key: c.Children[0].Token.Text, eq: eq, line: line})
+		}
+		line += strings.Count(c.String(), "\n")
+	}
+	return entries
+}
+
+// objectEntries returns entries of object expression '{ key = value ... }'
+func objectEntries(expr *CSTNode) ([]cstEntry, error) {
+	list := expr.Children
+	if list[0].Token.Text != "{" || list[len(list)-1].Token.Text != "}" {
+		return nil, fmt.Errorf("object expected, found %#q", expr.String())
+	}
+	var entries []cstEntry
+	depth, line := 0, 0
+	expectKey := false
+	for i := 0; i < len(list); i++ {
+		t := list[i].Token
+		switch {
+		case t.Kind == TokenNewline:
+			line++
+			expectKey = expectKey || depth == 1
+		case t.isTrivia():
+			line += strings.Count(t.Text, "\n")
+		case t.Kind == TokenSymbol && strings.Contains("{[(", t.Text):
+			depth++
+			expectKey = depth == 1
+		case t.Kind == TokenSymbol && strings.Contains("}])", t.Text):
+			depth--
+		case t.Kind == TokenSymbol && t.Text == "," && depth == 1:
+			expectKey = true
+		case expectKey && depth == 1:
+			expectKey = false
+			e := cstEntry{owner: expr, start: i, line: line}
+			for i < len(list) && (list[i].Token.Kind == TokenIdentifier || list[i].Token.Kind == TokenString || list[i].Token.Text == ".") {
+				e.key += list[i].Token.Text
+				i++
+			}
+			for i < len(list) && list[i].Token.Kind == TokenWhitespace {
+				i++
+			}
+			if i == len(list) || list[i].Token.Text != "=" {
+				return nil, fmt.Errorf("'=' expected after %#q", e.key)
+			}
+			e.eq = i
+			for i++; i < len(list) && list[i].Token.Kind == TokenWhitespace; i++ {
+			}
+			e.valueStart = i
+			// value ends with newline, comment, ',' or closing brace of the object
+			for d := 0; i < len(list); i++ {
+				t := list[i].Token
+				if d == 0 && (t.Kind == TokenNewline || t.Kind == TokenComment || t.Text == "," || t.Text == "}") {
+					break
+				}
+				if t.Kind == TokenSymbol && strings.Contains("{[(", t.Text) {
+					d++
+				} else if t.Kind == TokenSymbol && strings.Contains("}])", t.Text) {
+					d--
+				}
+				line += strings.Count(t.Text, "\n")
+			}
+			for i > e.valueStart && list[i-1].Token.Kind == TokenWhitespace {
+				i--
+			}
+			e.valueEnd = i
+			entries = append(entries, e)
+			i--
+		}
+	}
+	return entries, nil
+}
+
+// alignObject aligns '=' of the group of entry k of providers expr of module, which children were
+// old before the edit. If expr is no longer an object, the edit is reverted and an error is returned
+func alignObject(module string, expr *CSTNode, old []*CSTNode, k int) error {
+	entries, err := objectEntries(expr)
+	if err != nil {
+		expr.Children = old
+		return fmt.Errorf("Invalid providers of module %#q after edit: %v", module, err)
+	}
+	alignNeighbours(entries, k)
+	return nil
+}
+
+// alignBody aligns '=' of the group of attributes a belongs to
+func alignBody(body *CSTNode, a *CSTNode) {
+	entries := bodyEntries(body)
+	for k, e := range entries {
+		if e.owner == a {
+			alignGroup(entries, k)
+		}
+	}
+}
+
+// alignNeighbours aligns groups of entries k-1 and k, the ones around an inserted or removed line
+func alignNeighbours(entries []cstEntry, k int) {
+	if k > 0 && k <= len(entries) {
+		alignGroup(entries, k-1)
+	}
+	if k < len(entries) {
+		alignGroup(entries, k)
+	}
+}
+
+// alignGroup aligns '=' of the entries on consecutive lines around entry k,
+// the same way Format does
+func alignGroup(entries []cstEntry, k int) {
+	lo, hi := k, k+1
+	for lo > 0 && entries[lo-1].line+1 == entries[lo].line {
+		lo--
+	}
+	for hi < len(entries) && entries[hi-1].line+1 == entries[hi].line {
+		hi++
+	}
+	width := 0
+	for _, e := range entries[lo:hi] {
+		if len(e.key) > width {
+			width = len(e.key)
+		}
+	}
+	// entries of object expression share children, so they are padded from the last one
+	for i := hi - 1; i >= lo; i-- {
+		e := entries[i]
+		padding := strings.Repeat(" ", width-len(e.key)+1)
+		list := e.owner.Children
+		if ws := list[e.eq-1]; isTokenKind(ws, TokenWhitespace) {
+			if ws.Token.Text != padding {
+				list[e.eq-1] = wsNode(padding)
+			}
+			continue
+		}
+		e.owner.Children = append(list[:e.eq], append([]*CSTNode{wsNode(padding)}, list[e.eq:]...)...)
+	}
+}
+
+// removeLine removes list[start:end] along with the rest of its line, including trailing
+// comment. If there is something else on the line, only the entry and its separator are removed
+func removeLine(list []*CSTNode, start, end int) []*CSTNode {
+	lineStart := start
+	if lineStart > 0 && isTokenKind(list[lineStart-1], TokenWhitespace) {
+		lineStart--
+	}
+	lineEnd := end
+	for lineEnd < len(list) && (isTokenKind(list[lineEnd], TokenWhitespace) ||
+		isTokenKind(list[lineEnd], TokenComment) && !strings.HasPrefix(list[lineEnd].Token.Text, "/*")) {
+		lineEnd++
+	}
+	if (lineStart == 0 || isTokenKind(list[lineStart-1], TokenNewline)) && lineEnd < len(list) && isTokenKind(list[lineEnd], TokenNewline) {
+		return append(list[:lineStart], list[lineEnd+1:]...)
+	}
+	if end < len(list) && isTokenKind(list[end], TokenSymbol) && list[end].Token.Text == "," {
+		end++
+		for end < len(list) && isTokenKind(list[end], TokenWhitespace) {
+			end++
+		}
+	} else if start > 0 && isTokenKind(list[start-1], TokenSymbol) && list[start-1].Token.Text == "," {
+		start--
+	} else {
+		start = lineStart
+	}
+	return append(list[:start], list[end:]...)
+}
+
+// insertAttribute inserts attribute a into block on a new line after anchor, or at the beginning
+// of the block if anchor is nil
+func insertAttribute(block, anchor, a *CSTNode) {
+	body := block.Body()
+	indent := attributeIndent(block)
+	at := -1
+	for i, c := range body.Children {
+		if anchor != nil && c == anchor || anchor == nil && i == 0 {
+			at = indexOfKind(body.Children, i, TokenNewline)
+			break
+		}
+	}
+	if at < 0 {
+		// single line block
+		var items []*CSTNode
+		for _, c := range body.Children {
+			if !isTrivia(c) {
+				items = append(items, c)
+			}
+		}
+		if anchor == nil {
+			items = append([]*CSTNode{a}, items...)
+		} else {
+			items = append(items, a)
+		}
+		var children []*CSTNode
+		for _, c := range items {
+			children = append(children, newlineNode(), wsNode(indent), c)
+		}
+		body.Children = append(children, newlineNode(), wsNode(strings.TrimSuffix(indent, "  ")))
+		return
+	}
+	at++
+	body.Children = append(body.Children[:at], append([]*CSTNode{wsNode(indent), a, newlineNode()}, body.Children[at:]...)...)
+}
+
+// attributeIndent returns indentation of attributes of block
+func attributeIndent(block *CSTNode) string {
+	body := block.Body()
+	for i, c := range body.Children {
+		if c.Kind == CSTAttribute && i > 1 && isTokenKind(body.Children[i-2], TokenNewline) && isTokenKind(body.Children[i-1], TokenWhitespace) {
+			return body.Children[i-1].Token.Text
+		}
+	}
+	indent := ""
+	if column := block.Pos().Column; column > 1 {
+		indent = strings.Repeat(" ", column-1)
+	}
+	return indent + "  "
+}
+
+// indexOfKind returns index of the first token of kind in list starting from i, -1 if none
+func indexOfKind(list []*CSTNode, i int, kind TokenKind) int {
+	for ; i < len(list); i++ {
+		if isTokenKind(list[i], kind) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseCSTValue parses single expression, ',' outside of brackets would separate it from the
+// next entry of object expression, so it is not allowed
+func parseCSTValue(value string) (*CSTNode, error) {
+	f, err := ParseCST([]byte("value = "+value), "")
+	if err == nil && (len(f.Body.Children) != 1 || f.Body.Children[0].Kind != CSTAttribute) {
+		err = fmt.Errorf("single expression expected")
+	}
+	if err == nil {
+		depth := 0
+		for _, t := range f.Body.Children[0].Value().Tokens() {
+			switch {
+			case t.Kind != TokenSymbol:
+			case strings.Contains("{[(", t.Text):
+				depth++
+			case strings.Contains("}])", t.Text):
+				depth--
+			case t.Text == "," && depth == 0:
+				err = fmt.Errorf("single expression expected, found %#q outside of brackets", ",")
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid value %#q: %v", value, err)
+	}
+	return f.Body.Children[0].Value(), nil
+}
+
+// lexCSTKey splits key of object expression, e.g. 'aws.alice', into tokens
+func lexCSTKey(key string) ([]*CSTNode, error) {
+	tokens, err := newParser(key, "", &TFconfig{}).popTokens()
+	if err != nil || len(tokens) == 0 {
+		return nil, fmt.Errorf("Invalid key %#q", key)
+	}
+	var nodes []*CSTNode
+	for _, t := range tokens {
+		if t.Kind != TokenIdentifier && t.Text != "." {
+			return nil, fmt.Errorf("Invalid key %#q", key)
+		}
+		nodes = append(nodes, tokenNode(t))
+	}
+	return nodes, nil
+}
+
+func isCSTIdentifier(name string) bool {
+	tokens, err := newParser(name, "", &TFconfig{}).popTokens()
+	return err == nil && len(tokens) == 1 && tokens[0].Kind == TokenIdentifier && isIdentifierStart(name[0])
+}
+
+func newCSTAttribute(name string, expr *CSTNode) *CSTNode {
+	return &CSTNode{Kind: CSTAttribute, Children: []*CSTNode{
+		tokenNode(&Token{Kind: TokenIdentifier, Text: name}),
+		wsNode(" "),
+		tokenNode(&Token{Kind: TokenSymbol, Text: "="}),
+		wsNode(" "),
+		expr,
+	}}
+}
+
+func isTokenKind(n *CSTNode, kind TokenKind) bool {
+	return n.Kind == CSTToken && n.Token.Kind == kind
+}
+
+func isTrivia(n *CSTNode) bool {
+	return n.Kind == CSTToken && n.Token.isTrivia()
+}
+
+func wsNode(text string) *CSTNode {
+	return tokenNode(&Token{Kind: TokenWhitespace, Text: text})
+}
+
+func newlineNode() *CSTNode {
+	return tokenNode(&Token{Kind: TokenNewline, Text: "\n"})
+}
@@ -0,0 +1,164 @@
+package tfparser
+
+import (
+	"testing"
+)
+
+var editTestTFCode = `module "vpc" {
+  source = "../../modules/vpc" # pinned
+  name   = "main"
+
+  providers = {
+    aws.alice = aws.us-east-1 // first
+    aws.bob   = aws.ap-southeast-2
+  }
+}
+
+module "peering" {
+  source = "../../modules/peering"
+}
+
+module "empty" {}
+`
+
+func TestEditModules(t *testing.T) {
+	tests := []struct {
+		name     string
+		edit     func(f *CSTFile) error
+		expected string
+	}{
+		{
+			"set source",
+			func(f *CSTFile) error { return f.SetSource("vpc", "../../modules/vpc-v2") },
+			`module "vpc" {
+  source = "../../modules/vpc-v2" # pinned
+  name   = "main"
+`,
+		},
+		{
+			"add parameter",
+			func(f *CSTFile) error { return f.SetParameter("vpc", "cidr_block", `"10.0.0.0/16"`) },
+			`module "vpc" {
+  source     = "../../modules/vpc" # pinned
+  name       = "main"
+  cidr_block = "10.0.0.0/16"
+
+  providers = {
+`,
+		},
+		{
+			"remove parameter",
+			func(f *CSTFile) error { return f.RemoveParameter("vpc", "source") },
+			`module "vpc" {
+  name = "main"
+
+  providers = {
+`,
+		},
+		{
+			"set provider",
+			func(f *CSTFile) error { return f.SetProviderMapping("vpc", "aws.bob", "aws.eu-west-1") },
+			`    aws.alice = aws.us-east-1 // first
+    aws.bob   = aws.eu-west-1
+  }
+`,
+		},
+		{
+			"add provider",
+			func(f *CSTFile) error { return f.SetProviderMapping("vpc", "aws.carol_long", "aws.eu-west-1") },
+			`    aws.alice      = aws.us-east-1 // first
+    aws.bob        = aws.ap-southeast-2
+    aws.carol_long = aws.eu-west-1
+  }
+`,
+		},
+		{
+			"remove provider",
+			func(f *CSTFile) error { return f.RemoveProviderMapping("vpc", "aws.alice") },
+			`  providers = {
+    aws.bob = aws.ap-southeast-2
+  }
+`,
+		},
+		{
+			"add providers",
+			func(f *CSTFile) error { return f.SetProviderMapping("peering", "aws.peer", "aws.us-east-1") },
+			`module "peering" {
+  source = "../../modules/peering"
+
+  providers = {
+    aws.peer = aws.us-east-1
+  }
+}
+`,
+		},
+		{
+			"add parameter to single line block",
+			func(f *CSTFile) error { return f.SetParameter("empty", "source", `"./empty"`) },
+			`module "empty" {
+  source = "./empty"
+}
+`,
+		},
+	}
+	for _, test := range tests {
+		f, err := ParseCST([]byte(editTestTFCode), "main.tf")
+		if err != nil {
+			t.Fatalf("ParseCST returned an error, %v", err)
+		}
+		if err := test.edit(f); err != nil {
+			t.Errorf("%v: edit returned an error, %v", test.name, err)
+			continue
+		}
+		out := string(f.Bytes())
+		if !containsLines(out, test.expected) {
+			t.Errorf("%v: result does not contain\n%v\nresult:\n%v", test.name, test.expected, out)
+		}
+		// the rest of the file is not changed
+		if _, err := ParseString(out); err != nil {
+			t.Errorf("%v: unable to parse result, %v", test.name, err)
+		}
+		formatted, err := Format([]byte(out))
+		if err != nil {
+			t.Errorf("%v: unable to format result, %v", test.name, err)
+		} else if string(formatted) != out {
+			t.Errorf("%v: result is not formatted:\n%v", test.name, out)
+		}
+	}
+}
+
+func TestEditErrors(t *testing.T) {
+	f, err := ParseCST([]byte(editTestTFCode), "main.tf")
+	if err != nil {
+		t.Fatalf("ParseCST returned an error, %v", err)
+	}
+	for name, err := range map[string]error{
+		"unknown module":    f.SetSource("unknown", "./x"),
+		"invalid value":     f.SetParameter("vpc", "name", `"unterminated`),
+		"two values":        f.SetParameter("vpc", "name", "1\nb = 2"),
+		"invalid name":      f.SetParameter("vpc", "a-b.c", "1"),
+		"unknown parameter": f.RemoveParameter("vpc", "unknown"),
+		"unknown provider":  f.RemoveProviderMapping("vpc", "aws.unknown"),
+		"no providers":      f.RemoveProviderMapping("peering", "aws"),
+		"two providers":     f.SetProviderMapping("vpc", "aws.bob", "aws.y, b"),
+		"trailing comma":    f.SetParameter("vpc", "foo", "1,"),
+		"comma separated":   f.SetParameter("vpc", "foo", "1, 2"),
+	} {
+		if err == nil {
+			t.Errorf("%v: no error returned", name)
+		}
+	}
+	if string(f.Bytes()) != editTestTFCode {
+		t.Errorf("Failed edits changed the file:\n%s", f.Bytes())
+	}
+}
+
+// containsLines tells if s contains lines as a whole
+func containsLines(s, lines string) bool {
+	for i := 0; i+len(lines) <= len(s); i++ {
+		if (i == 0 || s[i-1] == '\n') && s[i:i+len(lines)] == lines {
+			return true
+		}
+	}
+	return false
+}
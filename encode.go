@@ -0,0 +1,156 @@
+package tfparser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the version of the schema used by WriteJSON, WriteYAML and WriteCSV.
+// It is incremented whenever a field is renamed or removed, or its meaning changes.
+// Adding new fields does not change the version
+const SchemaVersion = 1
+
+// WriteJSON writes config to w as indented JSON document. The document is an object with
+// 'schema_version' key (see SchemaVersion) and all the fields of TFconfig, as defined by
+// their json struct tags. All map keys are sorted, so the output is deterministic
+func WriteJSON(w io.Writer, config *TFconfig) error {
+	data, err := json.MarshalIndent(versioned(config), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteYAML writes config to w as YAML document. The schema is the same as for WriteJSON,
+// map keys are sorted and all strings are double quoted
+func WriteYAML(w io.Writer, config *TFconfig) error {
+	data, err := json.Marshal(versioned(config))
+	if err != nil {
+		return err
+	}
+	var doc map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	var b strings.Builder
+	writeYAMLMap(&b, doc, 0)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// CSVHeader is the header row written by WriteCSV
+var CSVHeader = []string{"module", "kind", "name", "value", "filename", "line", "column"}
+
+// WriteCSV writes module calls of config to w as CSV, one row per module attribute, see CSVHeader.
+// 'kind' is one of 'source', 'parameter' or 'provider'; 'name' is the name of a parameter or
+// provider alias and is empty for source. Rows are sorted by module name, kind and name
+func WriteCSV(w io.Writer, config *TFconfig) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+	row := func(module, kind, name, value string, pos Pos) error {
+		return cw.Write([]string{module, kind, name, value, pos.Filename, strconv.Itoa(pos.Line), strconv.Itoa(pos.Column)})
+	}
+	for _, name := range sortedModuleNames(config) {
+		m := config.Modules[name]
+		if err := row(name, "source", "", m.SourcePath, m.Pos); err != nil {
+			return err
+		}
+		for _, par := range sortedStringKeys(m.Parameters) {
+			if err := row(name, "parameter", par, m.Parameters[par], m.ParametersPos[par]); err != nil {
+				return err
+			}
+		}
+		for _, alias := range sortedStringKeys(m.Providers) {
+			if err := row(name, "provider", alias, m.Providers[alias], m.ProvidersPos[alias]); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func versioned(config *TFconfig) interface{} {
+	return struct {
+		SchemaVersion int `json:"schema_version"`
+		*TFconfig
+	}{SchemaVersion, config}
+}
+
+func writeYAMLMap(b *strings.Builder, m map[string]interface{}, indent int) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(strings.Repeat("  ", indent))
+		b.WriteString(yamlKey(k))
+		b.WriteString(":")
+		writeYAMLValue(b, m[k], indent)
+	}
+}
+
+// writeYAMLValue writes v which follows a key or a list item marker
+func writeYAMLValue(b *strings.Builder, v interface{}, indent int) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAMLMap(b, v, indent+1)
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		for _, item := range v {
+			b.WriteString(strings.Repeat("  ", indent+1))
+			b.WriteString("-")
+			writeYAMLValue(b, item, indent+1)
+		}
+	case string:
+		fmt.Fprintf(b, " %v\n", strconv.Quote(v))
+	case nil:
+		b.WriteString(" null\n")
+	default: // json.Number and bool
+		fmt.Fprintf(b, " %v\n", v)
+	}
+}
+
+// yamlKey returns k as is if it can not be read as anything but string, otherwise k is quoted
+func yamlKey(k string) string {
+	switch strings.ToLower(k) {
+	case "", "true", "false", "yes", "no", "on", "off", "y", "n", "null":
+		return strconv.Quote(k)
+	}
+	for i, r := range k {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' || i > 0 && r >= '0' && r <= '9') {
+			return strconv.Quote(k)
+		}
+	}
+	return k
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
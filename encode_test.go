@@ -0,0 +1,114 @@
+package tfparser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var encodeTestTFCode = `module "vpc" {
+  source = "../../modules/vpc"
+  name   = "main"
+
+  providers = {
+    aws = aws.us-east-1
+  }
+}
+`
+
+func TestWriteJSON(t *testing.T) {
+	config, err := ParseString(encodeTestTFCode)
+	if err != nil {
+		t.Fatalf("ParseString returned an error, %v", err)
+	}
+	var b strings.Builder
+	if err := WriteJSON(&b, config); err != nil {
+		t.Fatalf("WriteJSON returned an error, %v", err)
+	}
+	var doc struct {
+		SchemaVersion int `json:"schema_version"`
+		TFconfig
+	}
+	if err := json.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatalf("WriteJSON produced invalid JSON: %v", err)
+	}
+	if doc.SchemaVersion != SchemaVersion {
+		t.Fatalf("Unexpected schema version %v, expected %v", doc.SchemaVersion, SchemaVersion)
+	}
+	m, exists := doc.Modules["vpc"]
+	if !exists {
+		t.Fatal("Module 'vpc' was not found in JSON")
+	}
+	if m.SourcePath != "../../modules/vpc" || m.Parameters["name"] != "main" || m.ProvidersPos["aws"].Line != 6 {
+		t.Fatalf("Unexpected module 'vpc' decoded from JSON: %+v", m)
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	config, err := ParseString(encodeTestTFCode)
+	if err != nil {
+		t.Fatalf("ParseString returned an error, %v", err)
+	}
+	var b strings.Builder
+	if err := WriteYAML(&b, config); err != nil {
+		t.Fatalf("WriteYAML returned an error, %v", err)
+	}
+	expected := `modules:
+  vpc:
+    parameters:
+      name: "main"
+    parameters_pos:
+      name:
+        column: 3
+        line: 3
+    pos:
+      column: 1
+      line: 1
+    providers:
+      aws: "aws.us-east-1"
+    providers_pos:
+      aws:
+        column: 5
+        line: 6
+    source: "../../modules/vpc"
+objects:
+  "module.vpc":
+    address: "module.vpc"
+    pos:
+      column: 1
+      line: 1
+    references:
+      - "aws.us-east-1"
+schema_version: 1
+`
+	if b.String() != expected {
+		t.Fatalf("Unexpected YAML:\n%v\nexpected:\n%v", b.String(), expected)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	config, err := ParseFile("testdata/tf/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	var b strings.Builder
+	if err := WriteCSV(&b, config); err != nil {
+		t.Fatalf("WriteCSV returned an error, %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	expected := map[int]string{
+		0:  "module,kind,name,value,filename,line,column",
+		1:  "module1,source,,../../modules/module_name,testdata/tf/main.tf,4,1",
+		2:  "module1,parameter,alice_vpc_name,Development VPC,testdata/tf/main.tf,6,3",
+		7:  "module1,provider,aws.bob,aws.ap-southeast-2,testdata/tf/main.tf,16,5",
+		12: "module2,provider,aws.bob,aws.ap-southeast-2,testdata/tf/main.tf,36,5",
+	}
+	if len(lines) != 13 {
+		t.Fatalf("Unexpected number of CSV lines %v, expected 13:\n%v", len(lines), b.String())
+	}
+	for i, line := range expected {
+		if lines[i] != line {
+			t.Fatalf("Unexpected CSV line %v: %#q, expected %#q", i, lines[i], line)
+		}
+	}
+}
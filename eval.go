@@ -0,0 +1,1221 @@
+package tfparser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Value is a value of evaluated expression: nil for null, string, float64, bool, []Value for
+// lists, tuples and sets, map[string]Value for maps and objects, or Unknown
+type Value interface{}
+
+type unknownValue struct{}
+
+func (unknownValue) String() string { return "(known after apply)" }
+
+func (unknownValue) MarshalJSON() ([]byte, error) { return []byte(`"(known after apply)"`), nil }
+
+// Unknown is a value which is known only after apply, e.g. an attribute of a resource or an
+// output of a module. Operations on unknown values result in Unknown rather than an error
+var Unknown Value = unknownValue{}
+
+// EvalContext holds values of references expressions are evaluated with
+type EvalContext struct {
+	Variables map[string]Value // input variables, referenced as 'var.name'
+	Locals    map[string]Value // local values, referenced as 'local.name'
+	Each      map[string]Value // 'key' and 'value' of for_each instance, nil outside of for_each
+	Count     map[string]Value // 'index' of count instance, nil outside of count
+}
+
+// Eval evaluates expression expr as it is written in configuration, e.g. 'cidrsubnet(var.cidr, 8, 1)'.
+// Literals, templates, operators, conditionals, for expressions, splats and pure built-in functions
+// are supported. References to objects which are not in ctx, e.g. resources, data sources or
+// outputs of modules, are Unknown, as are results of impure functions like 'timestamp()'. ctx may be nil
+func Eval(expr string, ctx *EvalContext) (Value, error) {
+	e, err := parseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	if ctx == nil {
+		ctx = &EvalContext{}
+	}
+	return e.eval(&evalScope{ctx: ctx})
+}
+
+// FormatValue returns v as it would be written in configuration, e.g. '"main"' or '[1, 2]'.
+// Unknown is formatted as '(known after apply)'
+func FormatValue(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return formatNumber(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []Value:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = FormatValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]Value:
+		if len(v) == 0 {
+			return "{}"
+		}
+		items := make([]string, 0, len(v))
+		for _, key := range sortedValueKeys(v) {
+			name := key
+			if !identifierRe.MatchString(key) {
+				name = strconv.Quote(key)
+			}
+			items = append(items, name+" = "+FormatValue(v[key]))
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	}
+	return fmt.Sprint(v)
+}
+
+var identifierRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// exprNode is a node of expression syntax tree
+type exprNode interface {
+	eval(s *evalScope) (Value, error)
+}
+
+// evalScope holds names of for expressions and splats along with the context
+type evalScope struct {
+	ctx    *EvalContext
+	names  map[string]Value
+	parent *evalScope
+}
+
+func (s *evalScope) with(names map[string]Value) *evalScope {
+	return &evalScope{s.ctx, names, s}
+}
+
+// splatItem is the name current item of splat expression is bound to, it is not an identifier
+const splatItem = " splat"
+
+type literalNode struct {
+	v Value
+}
+
+func (n *literalNode) eval(s *evalScope) (Value, error) { return n.v, nil }
+
+// templateNode is a string with interpolations, parts are literal strings and interpolated expressions
+type templateNode struct {
+	parts []exprNode
+}
+
+func (n *templateNode) eval(s *evalScope) (Value, error) {
+	var b strings.Builder
+	unknown := false
+	for _, part := range n.parts {
+		v, err := part.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		if isUnknown(v) {
+			unknown = true
+			continue
+		}
+		if v == nil {
+			return nil, fmt.Errorf("Invalid template interpolation value: null")
+		}
+		str, err := toString(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid template interpolation value: %v", err)
+		}
+		b.WriteString(str)
+	}
+	if unknown {
+		return Unknown, nil
+	}
+	return b.String(), nil
+}
+
+// refNode is the first name of a traversal, e.g. 'var' of 'var.cidr'
+type refNode struct {
+	name string
+}
+
+func (n *refNode) eval(s *evalScope) (Value, error) {
+	for cur := s; cur != nil; cur = cur.parent {
+		if v, exists := cur.names[n.name]; exists {
+			return v, nil
+		}
+	}
+	switch n.name {
+	case "var":
+		return valueMap(s.ctx.Variables), nil
+	case "local":
+		return valueMap(s.ctx.Locals), nil
+	case "each":
+		if s.ctx.Each == nil {
+			return nil, fmt.Errorf("Reference to %#q outside of a block with for_each", n.name)
+		}
+		return s.ctx.Each, nil
+	case "count":
+		if s.ctx.Count == nil {
+			return nil, fmt.Errorf("Reference to %#q outside of a block with count", n.name)
+		}
+		return s.ctx.Count, nil
+	}
+	// resources, data sources, modules, 'path' and 'terraform' are known only to terraform
+	return Unknown, nil
+}
+
+func valueMap(m map[string]Value) map[string]Value {
+	if m == nil {
+		return map[string]Value{}
+	}
+	return m
+}
+
+type attrNode struct {
+	obj  exprNode
+	name string
+}
+
+func (n *attrNode) eval(s *evalScope) (Value, error) {
+	obj, err := n.obj.eval(s)
+	if err != nil || isUnknown(obj) {
+		return obj, err
+	}
+	switch obj := obj.(type) {
+	case map[string]Value:
+		if v, exists := obj[n.name]; exists {
+			return v, nil
+		}
+		if ref, ok := n.obj.(*refNode); ok {
+			switch ref.name {
+			case "var":
+				return nil, fmt.Errorf("Reference to undeclared input variable %#q", n.name)
+			case "local":
+				return nil, fmt.Errorf("Reference to undeclared local value %#q", n.name)
+			}
+		}
+		return nil, fmt.Errorf("Unsupported attribute %#q", n.name)
+	case nil:
+		return nil, fmt.Errorf("Attempt to get attribute %#q of null value", n.name)
+	}
+	return nil, fmt.Errorf("Unsupported attribute %#q of %v", n.name, typeName(obj))
+}
+
+type indexNode struct {
+	obj, key exprNode
+}
+
+func (n *indexNode) eval(s *evalScope) (Value, error) {
+	obj, err := n.obj.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	key, err := n.key.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if isUnknown(obj) || isUnknown(key) {
+		return Unknown, nil
+	}
+	switch obj := obj.(type) {
+	case []Value:
+		i, err := toInt(key)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid index: %v", err)
+		}
+		if i < 0 || i >= len(obj) {
+			return nil, fmt.Errorf("Invalid index %v, list has %v elements", i, len(obj))
+		}
+		return obj[i], nil
+	case map[string]Value:
+		k, err := toString(key)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid key: %v", err)
+		}
+		v, exists := obj[k]
+		if !exists {
+			return nil, fmt.Errorf("Invalid index, key %#q does not exist", k)
+		}
+		return v, nil
+	case nil:
+		return nil, fmt.Errorf("Attempt to index null value")
+	}
+	return nil, fmt.Errorf("Unable to index %v", typeName(obj))
+}
+
+// splatNode applies each to all elements of source, e.g. 'aws_subnet.x[*].id'
+type splatNode struct {
+	source, each exprNode
+}
+
+func (n *splatNode) eval(s *evalScope) (Value, error) {
+	src, err := n.source.eval(s)
+	if err != nil || isUnknown(src) {
+		return src, err
+	}
+	var items []Value
+	switch src := src.(type) {
+	case nil:
+	case []Value:
+		items = src
+	default:
+		// splat of a single value makes a list of it
+		items = []Value{src}
+	}
+	result := []Value{}
+	for _, item := range items {
+		v, err := n.each.eval(s.with(map[string]Value{splatItem: item}))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+type callNode struct {
+	name   string
+	args   []exprNode
+	expand bool // last argument is expanded with '...'
+}
+
+func (n *callNode) eval(s *evalScope) (Value, error) {
+	switch n.name {
+	case "try":
+		for _, arg := range n.args {
+			if v, err := arg.eval(s); err == nil {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("Call to function %#q failed: no expression succeeded", n.name)
+	case "can":
+		if len(n.args) != 1 {
+			return nil, fmt.Errorf("Call to function %#q failed: exactly 1 argument expected", n.name)
+		}
+		v, err := n.args[0].eval(s)
+		if err == nil && isUnknown(v) {
+			return Unknown, nil
+		}
+		return err == nil, nil
+	}
+	if impureFunctions[n.name] {
+		return Unknown, nil
+	}
+	f, exists := evalFunctions[n.name]
+	if !exists {
+		return nil, fmt.Errorf("Call to unknown function %#q", n.name)
+	}
+	var args []Value
+	for i, arg := range n.args {
+		v, err := arg.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		if n.expand && i == len(n.args)-1 {
+			if isUnknown(v) {
+				return Unknown, nil
+			}
+			list, err := toList(v)
+			if err != nil {
+				return nil, fmt.Errorf("Call to function %#q failed: invalid expanding argument: %v", n.name, err)
+			}
+			args = append(args, list...)
+			continue
+		}
+		args = append(args, v)
+	}
+	for _, arg := range args {
+		// length of a list does not depend on its elements
+		if isUnknown(arg) || containsUnknown(arg) && n.name != "length" {
+			return Unknown, nil
+		}
+	}
+	v, err := f(args)
+	if err != nil {
+		return nil, fmt.Errorf("Call to function %#q failed: %v", n.name, err)
+	}
+	return v, nil
+}
+
+type unaryNode struct {
+	op string
+	x  exprNode
+}
+
+func (n *unaryNode) eval(s *evalScope) (Value, error) {
+	x, err := n.x.eval(s)
+	if err != nil || isUnknown(x) {
+		return x, err
+	}
+	if n.op == "!" {
+		b, err := toBool(x)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid operand of %#q: %v", n.op, err)
+		}
+		return !b, nil
+	}
+	f, err := toNumber(x)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid operand of %#q: %v", n.op, err)
+	}
+	return -f, nil
+}
+
+type binaryNode struct {
+	op   string
+	x, y exprNode
+}
+
+func (n *binaryNode) eval(s *evalScope) (Value, error) {
+	x, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	y, err := n.y.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	if containsUnknown(x) || containsUnknown(y) {
+		return Unknown, nil
+	}
+	switch n.op {
+	case "==":
+		return valuesEqual(x, y), nil
+	case "!=":
+		return !valuesEqual(x, y), nil
+	case "&&", "||":
+		a, err := toBool(x)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid operand of %#q: %v", n.op, err)
+		}
+		b, err := toBool(y)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid operand of %#q: %v", n.op, err)
+		}
+		if n.op == "&&" {
+			return a && b, nil
+		}
+		return a || b, nil
+	}
+	a, err := toNumber(x)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid operand of %#q: %v", n.op, err)
+	}
+	b, err := toNumber(y)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid operand of %#q: %v", n.op, err)
+	}
+	switch n.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/", "%":
+		if b == 0 {
+			return nil, fmt.Errorf("Division by zero")
+		}
+		if n.op == "%" {
+			return math.Mod(a, b), nil
+		}
+		return a / b, nil
+	case "<":
+		return a < b, nil
+	case ">":
+		return a > b, nil
+	case "<=":
+		return a <= b, nil
+	case ">=":
+		return a >= b, nil
+	}
+	return nil, fmt.Errorf("Unsupported operator %#q", n.op)
+}
+
+type conditionalNode struct {
+	cond, a, b exprNode
+}
+
+func (n *conditionalNode) eval(s *evalScope) (Value, error) {
+	cond, err := n.cond.eval(s)
+	if err != nil || isUnknown(cond) {
+		return cond, err
+	}
+	b, err := toBool(cond)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid condition: %v", err)
+	}
+	if b {
+		return n.a.eval(s)
+	}
+	return n.b.eval(s)
+}
+
+type tupleNode struct {
+	items []exprNode
+}
+
+func (n *tupleNode) eval(s *evalScope) (Value, error) {
+	list := make([]Value, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+type objectNode struct {
+	keys, values []exprNode
+}
+
+func (n *objectNode) eval(s *evalScope) (Value, error) {
+	obj := make(map[string]Value, len(n.keys))
+	unknown := false
+	for i, keyNode := range n.keys {
+		key, err := keyNode.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		v, err := n.values[i].eval(s)
+		if err != nil {
+			return nil, err
+		}
+		if isUnknown(key) {
+			unknown = true
+			continue
+		}
+		k, err := toString(key)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid object key: %v", err)
+		}
+		if _, exists := obj[k]; exists {
+			return nil, fmt.Errorf("Duplicated object key %#q", k)
+		}
+		obj[k] = v
+	}
+	if unknown {
+		return Unknown, nil
+	}
+	return obj, nil
+}
+
+// forNode is '[for k, v in coll : value if cond]' or '{for k, v in coll : key => value if cond}'
+type forNode struct {
+	keyVar, valueVar string
+	coll             exprNode
+	key              exprNode // nil for list result
+	value, cond      exprNode
+	group            bool // values with the same key are grouped with '...'
+}
+
+func (n *forNode) eval(s *evalScope) (Value, error) {
+	coll, err := n.coll.eval(s)
+	if err != nil || isUnknown(coll) {
+		return coll, err
+	}
+	var keys, values []Value
+	switch coll := coll.(type) {
+	case []Value:
+		for i, v := range coll {
+			keys, values = append(keys, float64(i)), append(values, v)
+		}
+	case map[string]Value:
+		for _, k := range sortedValueKeys(coll) {
+			keys, values = append(keys, k), append(values, coll[k])
+		}
+	default:
+		return nil, fmt.Errorf("Unable to iterate over %v in for expression", typeName(coll))
+	}
+	list := []Value{}
+	obj := make(map[string]Value)
+	for i := range keys {
+		names := map[string]Value{n.valueVar: values[i]}
+		if n.keyVar != "" {
+			names[n.keyVar] = keys[i]
+		}
+		scope := s.with(names)
+		if n.cond != nil {
+			cond, err := n.cond.eval(scope)
+			if err != nil || isUnknown(cond) {
+				return cond, err
+			}
+			b, err := toBool(cond)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid condition of for expression: %v", err)
+			}
+			if !b {
+				continue
+			}
+		}
+		v, err := n.value.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		if n.key == nil {
+			list = append(list, v)
+			continue
+		}
+		key, err := n.key.eval(scope)
+		if err != nil || isUnknown(key) {
+			return key, err
+		}
+		k, err := toString(key)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid key of for expression: %v", err)
+		}
+		if n.group {
+			group, _ := obj[k].([]Value)
+			obj[k] = append(group, v)
+			continue
+		}
+		if _, exists := obj[k]; exists {
+			return nil, fmt.Errorf("Duplicated key %#q in for expression, use '...' to group values", k)
+		}
+		obj[k] = v
+	}
+	if n.key == nil {
+		return list, nil
+	}
+	return obj, nil
+}
+
+// exprParser builds expression syntax tree from significant tokens of the shared lexer
+type exprParser struct {
+	tokens []*Token
+	i      int
+}
+
+// parseExpression parses expression expr into syntax tree
+func parseExpression(expr string) (exprNode, error) {
+	tokens, err := newParser(expr, "", &TFconfig{}).popTokens()
+	if err != nil {
+		return nil, err
+	}
+	ep := &exprParser{}
+	for _, t := range tokens {
+		if !t.isTrivia() {
+			ep.tokens = append(ep.tokens, splitIdentifier(t)...)
+		}
+	}
+	if len(ep.tokens) == 0 {
+		return nil, fmt.Errorf("Expression expected")
+	}
+	e, err := ep.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := ep.peek(); t != nil {
+		return nil, fmt.Errorf("%v: Unexpected %#q after expression", t.Pos, t.Text)
+	}
+	return e, nil
+}
+
+var numberPrefixRe = regexp.MustCompile(`^[0-9]+([eE][+-]?[0-9]+)?`)
+
+// splitIdentifier splits identifier token of the lexer, which may contain '-', into minus signs,
+// numbers and identifier, e.g. '-1' into '-' and '1', or '5-3' into '5', '-' and '3'
+func splitIdentifier(t *Token) []*Token {
+	if t.Kind != TokenIdentifier {
+		return []*Token{t}
+	}
+	var tokens []*Token
+	text, pos := t.Text, t.Pos
+	for text != "" {
+		switch {
+		case text[0] == '-':
+			tokens = append(tokens, &Token{TokenSymbol, "-", pos})
+			text = text[1:]
+		case text[0] >= '0' && text[0] <= '9':
+			n := len(numberPrefixRe.FindString(text))
+			tokens = append(tokens, &Token{TokenIdentifier, text[:n], pos})
+			text = text[n:]
+		default:
+			return append(tokens, &Token{TokenIdentifier, text, pos})
+		}
+		pos.Column = t.Pos.Column + len(t.Text) - len(text)
+	}
+	return tokens
+}
+
+func (ep *exprParser) peek() *Token {
+	if ep.i >= len(ep.tokens) {
+		return nil
+	}
+	return ep.tokens[ep.i]
+}
+
+// peekIs tells if the next token is symbol or keyword text
+func (ep *exprParser) peekIs(text string) bool {
+	t := ep.peek()
+	return t != nil && t.Text == text && (t.Kind == TokenSymbol || t.Kind == TokenIdentifier)
+}
+
+func (ep *exprParser) accept(text string) bool {
+	if ep.peekIs(text) {
+		ep.i++
+		return true
+	}
+	return false
+}
+
+func (ep *exprParser) expect(text string) error {
+	if ep.accept(text) {
+		return nil
+	}
+	return ep.unexpected(fmt.Sprintf("%#q", text))
+}
+
+func (ep *exprParser) unexpected(expected string) error {
+	t := ep.peek()
+	if t == nil {
+		return fmt.Errorf("Unexpected end of expression, expected %v", expected)
+	}
+	return fmt.Errorf("%v: Unexpected %#q, expected %v", t.Pos, t.Text, expected)
+}
+
+func (ep *exprParser) identifier() (string, error) {
+	t := ep.peek()
+	if t == nil || t.Kind != TokenIdentifier || !isIdentifierStart(t.Text[0]) {
+		return "", ep.unexpected("identifier")
+	}
+	ep.i++
+	return t.Text, nil
+}
+
+func (ep *exprParser) parseExpr() (exprNode, error) {
+	cond, err := ep.parseBinary(0)
+	if err != nil || !ep.accept("?") {
+		return cond, err
+	}
+	a, err := ep.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := ep.expect(":"); err != nil {
+		return nil, err
+	}
+	b, err := ep.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionalNode{cond, a, b}, nil
+}
+
+// binaryOperators are binary operators by precedence, from the lowest one
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", ">", "<=", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (ep *exprParser) parseBinary(level int) (exprNode, error) {
+	if level == len(binaryOperators) {
+		return ep.parseUnary()
+	}
+	x, err := ep.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := ep.peek()
+		if t == nil || t.Kind != TokenSymbol || !containsString(binaryOperators[level], t.Text) {
+			return x, nil
+		}
+		ep.i++
+		y, err := ep.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{t.Text, x, y}
+	}
+}
+
+func (ep *exprParser) parseUnary() (exprNode, error) {
+	if t := ep.peek(); t != nil && t.Kind == TokenSymbol && (t.Text == "!" || t.Text == "-") {
+		ep.i++
+		x, err := ep.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{t.Text, x}, nil
+	}
+	e, err := ep.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return ep.parsePostfix(e, false)
+}
+
+// parsePostfix parses attribute access, indexes and splats following e. In attribute splat
+// '.*' only attribute access may follow
+func (ep *exprParser) parsePostfix(e exprNode, attrsOnly bool) (exprNode, error) {
+	for {
+		switch {
+		case ep.peekIs(".") && ep.i+1 < len(ep.tokens) && ep.tokens[ep.i+1].Text == "*":
+			ep.i += 2
+			each, err := ep.parsePostfix(&refNode{splatItem}, true)
+			if err != nil {
+				return nil, err
+			}
+			return &splatNode{e, each}, nil
+		case ep.peekIs("."):
+			ep.i++
+			t := ep.peek()
+			if t == nil || t.Kind != TokenIdentifier {
+				return nil, ep.unexpected("attribute name")
+			}
+			ep.i++
+			if t.Text[0] >= '0' && t.Text[0] <= '9' {
+				// legacy index, e.g. 'list.0'
+				n, _ := strconv.ParseFloat(t.Text, 64)
+				e = &indexNode{e, &literalNode{n}}
+				continue
+			}
+			e = &attrNode{e, t.Text}
+		case ep.peekIs("[") && !attrsOnly:
+			ep.i++
+			if ep.accept("*") {
+				if err := ep.expect("]"); err != nil {
+					return nil, err
+				}
+				each, err := ep.parsePostfix(&refNode{splatItem}, false)
+				if err != nil {
+					return nil, err
+				}
+				return &splatNode{e, each}, nil
+			}
+			key, err := ep.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := ep.expect("]"); err != nil {
+				return nil, err
+			}
+			e = &indexNode{e, key}
+		default:
+			return e, nil
+		}
+	}
+}
+
+func (ep *exprParser) parsePrimary() (exprNode, error) {
+	t := ep.peek()
+	if t == nil {
+		return nil, ep.unexpected("expression")
+	}
+	switch t.Kind {
+	case TokenString:
+		ep.i++
+		return parseTemplate(t.Text[1:len(t.Text)-1], true)
+	case TokenHeredoc:
+		ep.i++
+		return parseHeredoc(t.Text)
+	case TokenIdentifier:
+		ep.i++
+		switch {
+		case t.Text[0] >= '0' && t.Text[0] <= '9':
+			text := t.Text
+			if ep.peekIs(".") && ep.i+1 < len(ep.tokens) && numberPrefixRe.MatchString(ep.tokens[ep.i+1].Text) {
+				text += "." + ep.tokens[ep.i+1].Text
+				ep.i += 2
+			}
+			n, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%v: Invalid number %#q", t.Pos, text)
+			}
+			return &literalNode{n}, nil
+		case t.Text == "true" || t.Text == "false":
+			return &literalNode{t.Text == "true"}, nil
+		case t.Text == "null":
+			return &literalNode{nil}, nil
+		case ep.peekIs("("):
+			return ep.parseCall(t.Text)
+		}
+		return &refNode{t.Text}, nil
+	}
+	switch t.Text {
+	case "(":
+		ep.i++
+		e, err := ep.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return e, ep.expect(")")
+	case "[":
+		ep.i++
+		if ep.peekIs("for") {
+			return ep.parseFor("]")
+		}
+		tuple := &tupleNode{}
+		for !ep.accept("]") {
+			item, err := ep.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			tuple.items = append(tuple.items, item)
+			if !ep.accept(",") && !ep.peekIs("]") {
+				return nil, ep.unexpected("',' or ']'")
+			}
+		}
+		return tuple, nil
+	case "{":
+		ep.i++
+		if ep.peekIs("for") {
+			return ep.parseFor("}")
+		}
+		return ep.parseObject()
+	}
+	return nil, ep.unexpected("expression")
+}
+
+func (ep *exprParser) parseCall(name string) (exprNode, error) {
+	call := &callNode{name: name}
+	ep.i++ // '('
+	for !ep.accept(")") {
+		arg, err := ep.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if ep.accept("...") {
+			call.expand = true
+			ep.accept(",")
+			if err := ep.expect(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if !ep.accept(",") && !ep.peekIs(")") {
+			return nil, ep.unexpected("',' or ')'")
+		}
+	}
+	return call, nil
+}
+
+// parseObject parses object from the token after the opening brace. Keys which are single
+// identifiers are literal strings, other keys are expressions, e.g. '"${var.x}"' or '(var.x)'
+func (ep *exprParser) parseObject() (exprNode, error) {
+	obj := &objectNode{}
+	for !ep.accept("}") {
+		var key exprNode
+		if t := ep.peek(); t != nil && t.Kind == TokenIdentifier && ep.i+1 < len(ep.tokens) &&
+			(ep.tokens[ep.i+1].Text == "=" || ep.tokens[ep.i+1].Text == ":") {
+			ep.i++
+			key = &literalNode{t.Text}
+		} else {
+			var err error
+			if key, err = ep.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		if !ep.accept("=") && !ep.accept(":") {
+			return nil, ep.unexpected("'=' or ':'")
+		}
+		value, err := ep.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		obj.keys, obj.values = append(obj.keys, key), append(obj.values, value)
+		ep.accept(",")
+	}
+	return obj, nil
+}
+
+// parseFor parses for expression from 'for' keyword till closing bracket
+func (ep *exprParser) parseFor(closing string) (exprNode, error) {
+	ep.i++ // 'for'
+	n := &forNode{}
+	first, err := ep.identifier()
+	if err != nil {
+		return nil, err
+	}
+	n.valueVar = first
+	if ep.accept(",") {
+		if n.valueVar, err = ep.identifier(); err != nil {
+			return nil, err
+		}
+		n.keyVar = first
+	}
+	if err := ep.expect("in"); err != nil {
+		return nil, err
+	}
+	if n.coll, err = ep.parseExpr(); err != nil {
+		return nil, err
+	}
+	if err := ep.expect(":"); err != nil {
+		return nil, err
+	}
+	if closing == "}" {
+		if n.key, err = ep.parseExpr(); err != nil {
+			return nil, err
+		}
+		if err := ep.expect("=>"); err != nil {
+			return nil, err
+		}
+	}
+	if n.value, err = ep.parseExpr(); err != nil {
+		return nil, err
+	}
+	n.group = closing == "}" && ep.accept("...")
+	if ep.accept("if") {
+		if n.cond, err = ep.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	return n, ep.expect(closing)
+}
+
+// parseTemplate parses content of a string template. Escape sequences are interpreted in quoted
+// strings only, not in heredocs. Template with a single interpolation results in its value as is
+func parseTemplate(s string, escapes bool) (exprNode, error) {
+	var parts []exprNode
+	var literal strings.Builder
+	start := 0
+	flush := func(end int) {
+		text := s[start:end]
+		if escapes {
+			text = unescape(text)
+		}
+		literal.WriteString(text)
+	}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escapes && s[i] == '\\':
+			i++
+		case strings.HasPrefix(s[i:], "$${") || strings.HasPrefix(s[i:], "%%{"):
+			flush(i + 1)
+			i += 2
+			literal.WriteByte('{')
+			start = i + 1
+		case strings.HasPrefix(s[i:], "%{"):
+			return nil, fmt.Errorf("Template directives are not supported")
+		case strings.HasPrefix(s[i:], "${"):
+			flush(i)
+			end := scanTemplate(s, i+1)
+			if end > len(s) || s[end-1] != '}' {
+				return nil, fmt.Errorf("Unable to find closing brace of interpolation")
+			}
+			e, err := parseExpression(strings.TrimSuffix(strings.TrimPrefix(s[i+2:end-1], "~"), "~"))
+			if err != nil {
+				return nil, err
+			}
+			if literal.Len() > 0 {
+				parts = append(parts, &literalNode{literal.String()})
+				literal.Reset()
+			}
+			parts = append(parts, e)
+			i, start = end-1, end
+		}
+	}
+	flush(len(s))
+	if literal.Len() > 0 || len(parts) == 0 {
+		parts = append(parts, &literalNode{literal.String()})
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return &templateNode{parts}, nil
+}
+
+// parseHeredoc parses heredoc '<<EOT ... EOT' as template. Indentation of '<<-EOT' is removed
+func parseHeredoc(text string) (exprNode, error) {
+	eol := strings.IndexByte(text, '\n')
+	body := text[eol+1:]
+	content := body[:strings.LastIndexByte(body, '\n')+1]
+	if strings.HasPrefix(text, "<<-") {
+		lines := strings.SplitAfter(content, "\n")
+		indent := -1
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if n := len(line) - len(strings.TrimLeft(line, " \t")); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+		for i, line := range lines {
+			if len(line) >= indent && indent > 0 {
+				lines[i] = line[indent:]
+			}
+		}
+		content = strings.Join(lines, "")
+	}
+	return parseTemplate(content, false)
+}
+
+func isUnknown(v Value) bool {
+	_, unknown := v.(unknownValue)
+	return unknown
+}
+
+// containsUnknown tells if v is Unknown or a collection with unknown elements
+func containsUnknown(v Value) bool {
+	switch v := v.(type) {
+	case unknownValue:
+		return true
+	case []Value:
+		for _, item := range v {
+			if containsUnknown(item) {
+				return true
+			}
+		}
+	case map[string]Value:
+		for _, item := range v {
+			if containsUnknown(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeName(v Value) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []Value:
+		return "list"
+	case map[string]Value:
+		return "object"
+	case unknownValue:
+		return "unknown value"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// toString converts primitive value to string, as terraform does
+func toString(v Value) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return formatNumber(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("string required, found %v", typeName(v))
+}
+
+// toNumber converts number or string with a number to number
+func toNumber(v Value) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+		return 0, fmt.Errorf("number required, found %#q", v)
+	}
+	return 0, fmt.Errorf("number required, found %v", typeName(v))
+}
+
+// toInt converts whole number to int
+func toInt(v Value) (int, error) {
+	f, err := toNumber(v)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, fmt.Errorf("whole number required, found %v", formatNumber(f))
+	}
+	return int(f), nil
+}
+
+// toBool converts bool or string 'true' or 'false' to bool
+func toBool(v Value) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if v == "true" || v == "false" {
+			return v == "true", nil
+		}
+		return false, fmt.Errorf("bool required, found %#q", v)
+	}
+	return false, fmt.Errorf("bool required, found %v", typeName(v))
+}
+
+func toList(v Value) ([]Value, error) {
+	if list, ok := v.([]Value); ok {
+		return list, nil
+	}
+	return nil, fmt.Errorf("list required, found %v", typeName(v))
+}
+
+func toMap(v Value) (map[string]Value, error) {
+	if m, ok := v.(map[string]Value); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("map required, found %v", typeName(v))
+}
+
+// valuesEqual tells if values are of the same type and equal
+func valuesEqual(a, b Value) bool {
+	switch a := a.(type) {
+	case []Value:
+		list, ok := b.([]Value)
+		if !ok || len(list) != len(a) {
+			return false
+		}
+		for i := range a {
+			if !valuesEqual(a[i], list[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]Value:
+		m, ok := b.(map[string]Value)
+		if !ok || len(m) != len(a) {
+			return false
+		}
+		for k, v := range a {
+			if other, exists := m[k]; !exists || !valuesEqual(v, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func sortedValueKeys(m map[string]Value) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package tfparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	ctx := &EvalContext{
+		Variables: map[string]Value{
+			"cidr":  "10.0.0.0/16",
+			"env":   "dev",
+			"zones": []Value{"a", "b", "c"},
+			"tags":  map[string]Value{"team": "net", "cost-center": "42"},
+			"n":     3.0,
+			"id":    Unknown,
+		},
+		Locals: map[string]Value{"prefix": "app"},
+	}
+	tests := []struct {
+		expr, want string
+	}{
+		{`"main"`, `"main"`},
+		{`42`, `42`},
+		{`1.5`, `1.5`},
+		{`-1`, `-1`},
+		{`true`, `true`},
+		{`null`, `null`},
+		{`[1, "a", false]`, `[1, "a", false]`},
+		{`{ a = 1, "b c" = 2 }`, `{ a = 1, "b c" = 2 }`},
+		{`"a \"quoted\" ${var.env}"`, `"a \"quoted\" dev"`},
+		{`"$${literal}"`, `"${literal}"`},
+		{`"${var.n}"`, `3`},
+		{`"${local.prefix}-${var.env}"`, `"app-dev"`},
+		{`var.zones[1]`, `"b"`},
+		{`var.zones.0`, `"a"`},
+		{`var.tags["cost-center"]`, `"42"`},
+		{`var.tags.team`, `"net"`},
+		{`5-3`, `2`},
+		{`var.n * 2 + 1`, `7`},
+		{`1 + 2 * 3 == 7 && !false`, `true`},
+		{`(1 + 2) * 3`, `9`},
+		{`10 % 4`, `2`},
+		{`var.n > 2 ? "big" : "small"`, `"big"`},
+		{`var.env != "prod"`, `true`},
+		{`[for z in var.zones : upper(z)]`, `["A", "B", "C"]`},
+		{`[for i, z in var.zones : "${i}${z}" if z != "b"]`, `["0a", "2c"]`},
+		{`{for k, v in var.tags : v => k}`, `{ "42" = "cost-center", net = "team" }`},
+		{`{for z in ["a", "b", "a"] : z => z...}`, `{ a = ["a", "a"], b = ["b"] }`},
+		{`[{ n = 1 }, { n = 2 }][*].n`, `[1, 2]`},
+		{`[{ n = 1 }, { n = 2 }].*.n`, `[1, 2]`},
+		{`<<-EOT
+    hello ${var.env}
+      world
+    EOT`, `"hello dev\n  world\n"`},
+
+		{`lower("ABC")`, `"abc"`},
+		{`title("hello world-wide web_site 2nd")`, `"Hello World-Wide Web_site 2nd"`},
+		{`format("%s-%03d", var.env, 7)`, `"dev-007"`},
+		{`join(",", var.zones)`, `"a,b,c"`},
+		{`split(",", "a,b")`, `["a", "b"]`},
+		{`replace("a-b-c", "/-/", "_")`, `"a_b_c"`},
+		{`substr("hello", 1, 3)`, `"ell"`},
+		{`regex("([a-z]+)-([0-9]+)", "web-12")`, `["web", "12"]`},
+		{`length(var.zones)`, `3`},
+		{`concat(var.zones, ["d"])`, `["a", "b", "c", "d"]`},
+		{`contains(var.zones, "b")`, `true`},
+		{`element(var.zones, 4)`, `"b"`},
+		{`flatten([[1], [2, [3]]])`, `[1, 2, 3]`},
+		{`keys(var.tags)`, `["cost-center", "team"]`},
+		{`lookup(var.tags, "owner", "none")`, `"none"`},
+		{`merge(var.tags, { team = "ops" })`, `{ cost-center = "42", team = "ops" }`},
+		{`range(3)`, `[0, 1, 2]`},
+		{`zipmap(["a", "b"], [1, 2])`, `{ a = 1, b = 2 }`},
+		{`coalesce("", "x")`, `"x"`},
+		{`toset(["b", "a", "b"])`, `["a", "b"]`},
+		{`max([4, 9, 2]...)`, `9`},
+		{`max(1, 5, 3)`, `5`},
+		{`ceil(1.2)`, `2`},
+		{`parseint("ff", 16)`, `255`},
+		{`jsonencode({ a = [1, "x"] })`, `"{\"a\":[1,\"x\"]}"`},
+		{`jsondecode("{\"a\": true}")`, `{ a = true }`},
+		{`base64encode("hi")`, `"aGk="`},
+		{`md5("hi")`, `"49f68a5c8493ec2c0bf489821c21fc3b"`},
+		{`cidrsubnet(var.cidr, 8, 1)`, `"10.0.1.0/24"`},
+		{`cidrsubnet("fd00::/56", 8, 2)`, `"fd00:0:0:2::/64"`},
+		{`cidrhost("10.0.1.0/24", 5)`, `"10.0.1.5"`},
+		{`cidrhost("10.0.1.0/24", -2)`, `"10.0.1.254"`},
+		{`cidrnetmask("10.0.0.0/20")`, `"255.255.240.0"`},
+		{`cidrsubnets("10.1.0.0/16", 4, 4, 8, 4)`, `["10.1.0.0/20", "10.1.16.0/20", "10.1.32.0/24", "10.1.48.0/20"]`},
+		{`try(var.tags.owner, "none")`, `"none"`},
+		{`can(var.tags.owner)`, `false`},
+
+		// unknown values propagate
+		{`aws_vpc.main.id`, `(known after apply)`},
+		{`"vpc-${aws_vpc.main.id}"`, `(known after apply)`},
+		{`var.id == "x"`, `(known after apply)`},
+		{`upper(var.id)`, `(known after apply)`},
+		{`[var.id, "a"]`, `[(known after apply), "a"]`},
+		{`length([var.id, "a"])`, `2`},
+		{`join(",", [var.id])`, `(known after apply)`},
+		{`timestamp()`, `(known after apply)`},
+		{`module.vpc.id != null ? 1 : 2`, `(known after apply)`},
+	}
+	for _, tt := range tests {
+		v, err := Eval(tt.expr, ctx)
+		if err != nil {
+			t.Errorf("Eval(%v) returned an error, %v", tt.expr, err)
+			continue
+		}
+		if got := FormatValue(v); got != tt.want {
+			t.Errorf("Eval(%v) = %v, expected %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{`var.missing`, "undeclared input variable"},
+		{`local.missing`, "undeclared local value"},
+		{`each.key`, "outside of a block with for_each"},
+		{`count.index`, "outside of a block with count"},
+		{`"a" + 1`, "number required"},
+		{`[1, 2][5]`, "Invalid index"},
+		{`nosuchfunc(1)`, "unknown function"},
+		{`cidrsubnet("10.0.0.0/30", 8, 1)`, "insufficient address space"},
+		{`1 +`, "Unexpected end of expression"},
+		{`(1`, "Unexpected end of expression"},
+		{`1 2`, "Unexpected"},
+		{`"%{ if true }x%{ endif }"`, "directives are not supported"},
+	}
+	for _, tt := range tests {
+		_, err := Eval(tt.expr, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Eval(%v) returned error %v, expected %#q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestEvalEachAndCount(t *testing.T) {
+	ctx := &EvalContext{
+		Each:  map[string]Value{"key": "eu", "value": map[string]Value{"cidr": "10.1.0.0/16"}},
+		Count: map[string]Value{"index": 2.0},
+	}
+	v, err := Eval(`"${each.key}-${count.index}-${each.value.cidr}"`, ctx)
+	if err != nil {
+		t.Fatalf("Eval returned an error, %v", err)
+	}
+	if v != "eu-2-10.1.0.0/16" {
+		t.Errorf("Unexpected value %v", FormatValue(v))
+	}
+}
@@ -0,0 +1,328 @@
+package tfparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fmtLine is a line of a file being formatted
+type fmtLine struct {
+	text     string // line without indentation, or the whole line if verbatim
+	depth    int    // nesting level of the line, in brackets, braces and parentheses
+	verbatim bool   // line of heredoc or multiline comment, it is not changed
+	name     string // attribute name, empty if line is not an attribute
+	value    string // attribute value, possibly with trailing comment
+	multi    bool   // attribute value continues on the next lines after heredoc or template
+	opens    bool   // attribute value opens brackets, braces or parentheses closed on the next lines
+}
+
+// Format formats terraform configuration src the way 'terraform fmt' does: nested blocks and
+// expressions are indented with two spaces, '=' signs of consecutive attributes are aligned,
+// spaces in block headers and around '=' are normalised and trailing spaces are removed.
+// Comments are preserved, heredocs and multiline comments are kept as they are
+func Format(src []byte) ([]byte, error) {
+	lines, err := splitFmtLines(string(src))
+	if err != nil {
+		return nil, err
+	}
+	alignAttributes(lines)
+
+	var b strings.Builder
+	for _, l := range lines {
+		if !l.verbatim && l.text != "" {
+			b.WriteString(strings.Repeat("  ", l.depth))
+		}
+		b.WriteString(l.text)
+		b.WriteString("\n")
+	}
+	// leading and trailing blank lines are dropped
+	return []byte(strings.Trim(b.String(), "\n") + "\n"), nil
+}
+
+// splitFmtLines splits src into lines, formatting each one of them separately
+func splitFmtLines(src string) ([]*fmtLine, error) {
+	var lines []*fmtLine
+	depth := 0
+	heredoc := "" // closing marker of the heredoc we are in
+	comment := false
+	template := 0 // nesting level of braces of template sequence in quoted string we are in
+	for n, raw := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(raw)
+		switch {
+		case heredoc != "":
+			lines = append(lines, &fmtLine{text: raw, verbatim: true})
+			if trimmed == heredoc {
+				heredoc = ""
+			}
+			continue
+		case comment:
+			lines = append(lines, &fmtLine{text: strings.TrimRight(raw, " \t"), verbatim: true})
+			comment = !strings.Contains(raw, "*/")
+			continue
+		case template > 0:
+			// quoted string continued in template sequence
+			lines = append(lines, &fmtLine{text: strings.TrimRight(raw, " \t"), verbatim: true})
+			change, marker, open, level := scanFmtLine(raw, template)
+			depth += change
+			heredoc, comment, template = marker, open, level
+			if depth < 0 {
+				return nil, fmt.Errorf("Unexpected closing bracket at line %d", n+1)
+			}
+			continue
+		}
+
+		l := &fmtLine{depth: depth}
+		for _, c := range trimmed {
+			if !strings.ContainsRune(")]}", c) {
+				break
+			}
+			l.depth--
+		}
+		if l.depth < 0 {
+			return nil, fmt.Errorf("Unexpected closing bracket at line %d", n+1)
+		}
+		code, trailing := splitComment(trimmed)
+		change, marker, open, level := scanFmtLine(code+trailing, 0)
+		depth += change
+		heredoc, comment, template = marker, open, level
+		if depth < 0 {
+			return nil, fmt.Errorf("Unexpected closing bracket at line %d", n+1)
+		}
+
+		switch {
+		case code == "":
+			l.text = trailing
+		case isAttributeLine(code):
+			eq := attributeEquals(code)
+			l.name = strings.TrimSpace(code[:eq])
+			l.value = joinComment(strings.TrimSpace(code[eq+1:]), trailing)
+			l.multi = heredoc != "" || template > 0
+			l.opens = depth > l.depth
+			l.text = l.name + " = " + l.value
+		case strings.HasSuffix(code, "{") && isIdentifierStart(code[0]):
+			// block header: type and labels separated with single spaces
+			header := strings.Join(splitFields(strings.TrimSuffix(code, "{")), " ")
+			l.text = joinComment(header+" {", trailing)
+		default:
+			l.text = joinComment(code, trailing)
+		}
+		lines = append(lines, l)
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("Unable to find closing brace for block")
+	}
+	if heredoc != "" {
+		return nil, fmt.Errorf("Unable to find closing marker %#q of heredoc", heredoc)
+	}
+	if comment {
+		return nil, fmt.Errorf("Unable to find closing multiline comment")
+	}
+	if template > 0 {
+		return nil, fmt.Errorf("Unable to find closing brace of template sequence")
+	}
+	return lines, nil
+}
+
+// alignAttributes aligns '=' signs in groups of consecutive attributes of the same nesting level.
+// Attribute with heredoc or multiline template is the last one in its group, attribute which
+// opens brackets on its line is not aligned, as 'terraform fmt' does
+func alignAttributes(lines []*fmtLine) {
+	for i := 0; i < len(lines); {
+		if lines[i].name == "" || lines[i].opens {
+			i++
+			continue
+		}
+		j := i + 1
+		for ; j < len(lines) && !lines[j-1].multi; j++ {
+			if lines[j].name == "" || lines[j].opens || lines[j].depth != lines[i].depth {
+				break
+			}
+		}
+		width := 0
+		for _, l := range lines[i:j] {
+			if len(l.name) > width {
+				width = len(l.name)
+			}
+		}
+		for _, l := range lines[i:j] {
+			l.text = l.name + strings.Repeat(" ", width-len(l.name)) + " = " + l.value
+		}
+		i = j
+	}
+}
+
+// scanFmtLine returns change of nesting level by line, closing marker if line starts heredoc,
+// whether line ends inside of multiline comment and nesting level of braces of template sequence
+// which line ends in. template is the level line starts in, quoted string is continued then
+func scanFmtLine(line string, template int) (change int, heredoc string, comment bool, level int) {
+	i := 0
+	for template > 0 && i < len(line) {
+		if i, template = scanFmtTemplate(line, i, template); template == 0 {
+			i, template = scanFmtString(line, i)
+		}
+	}
+	if template > 0 {
+		return 0, "", false, template
+	}
+	for i < len(line) {
+		switch c := line[i]; {
+		case c == '"':
+			if i, template = scanFmtString(line, i+1); template > 0 {
+				return change, "", false, template
+			}
+		case c == '#' || strings.HasPrefix(line[i:], "//"):
+			return change, "", false, 0
+		case strings.HasPrefix(line[i:], "/*"):
+			end := strings.Index(line[i+2:], "*/")
+			if end < 0 {
+				return change, "", true, 0
+			}
+			i += end + 4
+		case strings.HasPrefix(line[i:], "<<"):
+			marker := strings.TrimSpace(strings.TrimPrefix(line[i+2:], "-"))
+			if marker != "" && !strings.ContainsAny(marker, symbols+whitespaces+"()") {
+				return change, marker, false, 0
+			}
+			i += 2
+		case strings.ContainsRune("{[(", rune(c)):
+			change++
+			i++
+		case strings.ContainsRune("}])", rune(c)):
+			change--
+			i++
+		default:
+			i++
+		}
+	}
+	return change, "", false, 0
+}
+
+// scanFmtString returns index right after the closing quote of string, which content starts at
+// line[i], or nesting level of braces of template sequence if line ends inside of it
+func scanFmtString(line string, i int) (int, int) {
+	for ; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, 0
+		case '$', '%':
+			if i+1 < len(line) && line[i+1] == '{' {
+				end, level := scanFmtTemplate(line, i+2, 1)
+				if level > 0 {
+					return end, level
+				}
+				i = end - 1
+			}
+		}
+	}
+	return len(line), 0
+}
+
+// scanFmtTemplate returns index right after the closing brace of template sequence continued at
+// line[i] with nesting level of braces template, or the level if line ends inside of it
+func scanFmtTemplate(line string, i, template int) (int, int) {
+	for ; i < len(line); i++ {
+		switch line[i] {
+		case '{':
+			template++
+		case '}':
+			if template--; template == 0 {
+				return i + 1, 0
+			}
+		case '"':
+			end, level := scanFmtString(line, i+1)
+			if level > 0 {
+				return end, template + level
+			}
+			i = end - 1
+		}
+	}
+	return len(line), template
+}
+
+// splitComment splits line into code and trailing single line comment
+func splitComment(line string) (code, comment string) {
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == '"':
+			i = scanString(line, i)
+		case c == '#' || strings.HasPrefix(line[i:], "//"):
+			return strings.TrimSpace(line[:i]), line[i:]
+		case strings.HasPrefix(line[i:], "<<"):
+			// the rest of the line is heredoc marker
+			return line, ""
+		default:
+			i++
+		}
+	}
+	return line, ""
+}
+
+func joinComment(code, comment string) string {
+	if comment == "" {
+		return code
+	}
+	if code == "" {
+		return comment
+	}
+	return code + " " + comment
+}
+
+// isAttributeLine tells if code starts with 'name =' where name is an identifier,
+// a traversal like 'aws.alice' or a quoted string
+func isAttributeLine(code string) bool {
+	return attributeEquals(code) > 0
+}
+
+// attributeEquals returns index of '=' after attribute name in code, or -1
+func attributeEquals(code string) int {
+	i := 0
+	switch {
+	case code[0] == '"':
+		i = scanString(code, 0)
+	case isIdentifierStart(code[0]):
+		for i < len(code) && (isIdentifierChar(code[i]) || code[i] == '.') {
+			i++
+		}
+	default:
+		return -1
+	}
+	for i < len(code) && (code[i] == ' ' || code[i] == '\t') {
+		i++
+	}
+	if i >= len(code) || code[i] != '=' || i+1 < len(code) && (code[i+1] == '=' || code[i+1] == '>') {
+		return -1
+	}
+	return i
+}
+
+// splitFields splits s by whitespace, which is not inside of strings
+func splitFields(s string) []string {
+	var fields []string
+	start := -1
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			if start >= 0 {
+				fields = append(fields, s[start:i])
+				start = -1
+			}
+			i++
+		case c == '"':
+			if start < 0 {
+				start = i
+			}
+			i = scanString(s, i)
+		default:
+			if start < 0 {
+				start = i
+			}
+			i++
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, s[start:])
+	}
+	return fields
+}
@@ -0,0 +1,121 @@
+package tfparser
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var formatTestTFCode = `
+
+resource   "aws_instance"    "web"{
+ami="abc" # comment
+    instance_type   =   "t2.micro"
+  tags = {
+   Name = "x"
+        LongerName = "y"
+  }
+user_data = <<EOT
+  keep   this
+EOT
+}
+/* kept
+      as is */
+variable "v" {}
+`
+
+var formatTestExpected = `resource "aws_instance" "web" {
+  ami           = "abc" # comment
+  instance_type = "t2.micro"
+  tags = {
+    Name       = "x"
+    LongerName = "y"
+  }
+  user_data = <<EOT
+  keep   this
+EOT
+}
+/* kept
+      as is */
+variable "v" {}
+`
+
+func TestFormat(t *testing.T) {
+	out, err := Format([]byte(formatTestTFCode))
+	if err != nil {
+		t.Fatalf("Format returned an error, %v", err)
+	}
+	if string(out) != formatTestExpected {
+		t.Fatalf("Unexpected result of Format:\n%v\nexpected:\n%v", string(out), formatTestExpected)
+	}
+	again, err := Format(out)
+	if err != nil {
+		t.Fatalf("Format returned an error on formatted code, %v", err)
+	}
+	if string(again) != string(out) {
+		t.Fatalf("Format is not idempotent:\n%v", string(again))
+	}
+}
+
+func TestFormatTemplate(t *testing.T) {
+	for src, expected := range map[string]string{
+		"x = \"${\n  foo}\"\n":                           "x = \"${\n  foo}\"\n",
+		"a=1\nlonger = \"${\nfoo}\"\nb = {\n c = 1\n}\n": "a      = 1\nlonger = \"${\nfoo}\"\nb = {\n  c = 1\n}\n",
+	} {
+		out, err := Format([]byte(src))
+		if err != nil {
+			t.Fatalf("Format returned an error for %#q, %v", src, err)
+		}
+		if string(out) != expected {
+			t.Errorf("Unexpected result of Format for %#q: %#q, expected %#q", src, string(out), expected)
+		}
+	}
+}
+
+func TestFormatFile(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/tf/main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Format(src)
+	if err != nil {
+		t.Fatalf("Format returned an error, %v", err)
+	}
+	for _, line := range []string{
+		`  boolean_value  = true`,
+		`  numeric_value  = 12`,
+		`    // aws.bob = aws.us-east-1 # - wrong value`,
+	} {
+		if !strings.Contains(string(out), line+"\n") {
+			t.Errorf("Formatted file does not contain line %#q", line)
+		}
+	}
+	// formatting must not change the parsed configuration
+	before, err := ParseString(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseString(string(out))
+	if err != nil {
+		t.Fatalf("Unable to parse formatted file, %v", err)
+	}
+	for name, m := range before.Modules {
+		if after.Modules[name] == nil || after.Modules[name].SourcePath != m.SourcePath {
+			t.Errorf("Module %#q changed by formatting", name)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	for _, src := range []string{
+		"module \"x\" {\n",
+		"}\n",
+		"a = <<EOT\nno marker\n",
+		"/* unterminated\n",
+		"x = \"${\n  foo\n",
+	} {
+		if _, err := Format([]byte(src)); err == nil {
+			t.Errorf("Format did not return an error for %#q", src)
+		}
+	}
+}
@@ -24,22 +24,77 @@ const (
 
 	stateModuleParameterName  // parameter passed to module read. Await for '='
 	stateModuleParameterValue // read '=' after reading parameter name. Await for parameter value
+
+	stateProviderName      // read token 'provider', await for provider name
+	stateProviderOpenBlock // read provider name, await for open curly brace
+
+	stateTerraformOpenBlock // read token 'terraform', await for open curly brace
+	stateTerraform          // inside terraform block
+	stateRequiredProviders  // inside 'required_providers' block of terraform block
+
+	stateLocals // inside locals block
 )
 
 // parser for stateTopf state
-// it skips/ignores all tokens/blocks except for 'module'
+// it parses 'module', 'provider', 'terraform' and 'locals' blocks, 'moved', 'import', 'removed'
+// and 'check' blocks are parsed by parseMetaBlock. All other blocks are read as generic blocks,
+// for resources, data sources, variables and outputs references are collected as well.
+// p.header collects block type and labels till the opening curly brace of the block
 func (p *parser) parseTopLevel() error {
 	switch p.state {
 	case stateTop:
-		switch strings.ToLower(p.peek()) {
+		tok := p.peek()
+		// keywords are only recognised as block type, not as block label
+		if len(p.header) > 0 && tok != "{" {
+			p.header = append(p.header, p.pop())
+			return nil
+		}
+		switch strings.ToLower(tok) {
 		case "module":
 			p.state = stateModuleName
+			p.curModPos = p.pos()
+			p.pop()
+		case "provider":
+			p.state = stateProviderName
+			p.curProvider = &Provider{Pos: p.pos()}
 			p.pop()
+		case "terraform":
+			p.state = stateTerraformOpenBlock
+			p.curTerraform = &Block{Type: "terraform", Pos: p.pos()}
+			p.pop()
+		case "locals":
+			p.pop()
+			p.err = p.popToken("{")
+			if p.err != nil {
+				return p.err
+			}
+			p.state = stateLocals
 		case "{":
-			p.skipBlock()
-		// default is a token we are not parsing right now.
+			if len(p.header) > 0 && isMetaBlock(p.header[0]) {
+				p.err = p.parseMetaBlock(p.header, p.headerPos)
+				p.header = nil
+				return p.err
+			}
+			if len(p.header) == 0 {
+				p.err = fmt.Errorf("%v: Unexpected %#q", p.pos(), "{")
+				return p.err
+			}
+			start := p.i
+			b, err := p.popBlock(p.header, p.headerPos)
+			if err != nil {
+				p.err = err
+				return p.err
+			}
+			p.config.Blocks = append(p.config.Blocks, b)
+			p.err = p.declareBlock(p.header, p.headerPos, p.data[start:p.i])
+			p.header = nil
+			if p.err != nil {
+				return p.err
+			}
+		// default is a block type we are not parsing right now.
 		default:
-			p.pop()
+			p.headerPos = p.pos()
+			p.header = append(p.header, p.pop())
 		}
 	case stateModuleName: // next token must be moudule name
 		if p.curModName != "" {
@@ -55,14 +110,28 @@ func (p *parser) parseTopLevel() error {
 			p.err = fmt.Errorf("Duplicated module name found: %#q", p.curModName)
 			return p.err
 		}
-		p.config.Modules[p.curModName] = &Module{make(map[string]string), make(map[string]string), ""}
+		p.config.Modules[p.curModName] = &Module{
+			Providers:     make(map[string]string),
+			Parameters:    make(map[string]string),
+			Pos:           p.curModPos,
+			ProvidersPos:  make(map[string]Pos),
+			ParametersPos: make(map[string]Pos),
+			exprs:         make(map[string]string),
+		}
 		p.state = stateModuleOpenBlock
 	case stateModuleOpenBlock:
 		p.err = p.popToken("{")
 		if p.err != nil {
 			return p.err
 		}
+		p.curModBodyStart = p.i
 		p.state = stateModule
+	case stateProviderName, stateProviderOpenBlock:
+		return p.parseProvider()
+	case stateTerraformOpenBlock, stateTerraform, stateRequiredProviders:
+		return p.parseTerraform()
+	case stateLocals:
+		return p.parseLocals()
 	default:
 		return p.parseModule()
 	}
@@ -83,6 +152,10 @@ func (p *parser) parseModule() error {
 			p.pop()
 			p.state = stateModuleProvidersDeclared
 		case "}":
+			p.err = p.declare("module."+p.curModName, p.curModPos, p.data[p.curModBodyStart:p.i])
+			if p.err != nil {
+				return p.err
+			}
 			p.pop()
 			p.curModName = ""
 			p.state = stateTop
@@ -91,6 +164,7 @@ func (p *parser) parseModule() error {
 				p.err = fmt.Errorf("FSM error, next token is to be parameter, but there is already parameter %#q", p.curModParName)
 				return p.err
 			}
+			p.curModParPos = p.pos()
 			p.curModParName = p.pop()
 			p.state = stateModuleParameterName
 		}
@@ -122,8 +196,22 @@ func (p *parser) parseModule() error {
 
 	// Read parameter
 	case stateModuleParameterName:
+		if p.peek() != "=" {
+			// nested block, e.g. 'lifecycle {}'
+			b, err := p.popNestedBlock(p.curModParName, p.curModParPos)
+			if err != nil {
+				p.err = err
+				return p.err
+			}
+			m := p.config.Modules[p.curModName]
+			m.Blocks = append(m.Blocks, b)
+			p.state = stateModule
+			p.curModParName = ""
+			return nil
+		}
 		p.popToken("=")
-		parValue := p.pop()
+		expr := p.popExpression()
+		parValue := unquote(expr)
 		// add parameters
 		if p.curModParName == "" {
 			p.err = fmt.Errorf("FSM error: got param value %#q, but param name is empty", parValue)
@@ -135,6 +223,8 @@ func (p *parser) parseModule() error {
 			return p.err
 		}
 		p.config.Modules[p.curModName].Parameters[p.curModParName] = parValue
+		p.config.Modules[p.curModName].ParametersPos[p.curModParName] = p.curModParPos
+		p.config.Modules[p.curModName].exprs[p.curModParName] = expr
 		p.state = stateModule
 		p.curModParName = ""
 	}
@@ -144,6 +234,7 @@ func (p *parser) parseModule() error {
 func (p *parser) parseProviders() error {
 	switch p.state {
 	case stateModuleProviders:
+		pos := p.peekPos()
 		tok := p.pop()
 		switch tok {
 		case "}":
@@ -159,7 +250,419 @@ func (p *parser) parseProviders() error {
 				return p.err
 			}
 			p.config.Modules[p.curModName].Providers[tok] = provName
+			p.config.Modules[p.curModName].ProvidersPos[tok] = pos
+		}
+	}
+	return nil
+}
+
+// parser for provider configuration block
+// body is read as generic block, 'alias' attribute is read into Provider.Alias
+func (p *parser) parseProvider() error {
+	switch p.state {
+	case stateProviderName:
+		p.curProvider.Name = p.pop()
+		p.state = stateProviderOpenBlock
+	case stateProviderOpenBlock:
+		p.err = p.popToken("{")
+		if p.err != nil {
+			return p.err
+		}
+		b := &Block{Type: "provider", Labels: []string{p.curProvider.Name}, Pos: p.curProvider.Pos}
+		if err := p.popBlockBody(b, true); err != nil {
+			p.err = fmt.Errorf("%v: %v", b.Pos, err)
+			return p.err
+		}
+		p.curProvider.Alias = b.Attributes["alias"]
+		p.curProvider.Attributes, p.curProvider.AttributesPos = b.Attributes, b.AttributesPos
+		p.curProvider.Blocks = b.Blocks
+		if p.config.Providers == nil {
+			p.config.Providers = make(map[string]*Provider)
+		}
+		addr := p.curProvider.Address()
+		if _, exists := p.config.Providers[addr]; exists {
+			p.err = fmt.Errorf("Duplicated provider configuration found: %#q", addr)
+			return p.err
 		}
+		p.config.Providers[addr] = p.curProvider
+		p.curProvider = nil
+		p.state = stateTop
+	}
+	return nil
+}
+
+// parser for terraform block
+// 'required_providers' block is read into RequiredProviders, other attributes and nested blocks,
+// e.g. 'backend', are read into generic block of type 'terraform'
+func (p *parser) parseTerraform() error {
+	switch p.state {
+	case stateTerraformOpenBlock:
+		p.err = p.popToken("{")
+		if p.err != nil {
+			return p.err
+		}
+		p.state = stateTerraform
+	case stateTerraform:
+		pos := p.peekPos()
+		switch tok := p.peek(); tok {
+		case "required_providers":
+			p.pop()
+			p.err = p.popToken("{")
+			if p.err != nil {
+				return p.err
+			}
+			p.state = stateRequiredProviders
+		case "{", "=", "":
+			p.err = fmt.Errorf("%v: Unexpected %#q in terraform block", pos, tok)
+			return p.err
+		case "}":
+			p.pop()
+			p.config.Blocks = append(p.config.Blocks, p.curTerraform)
+			p.curTerraform = nil
+			p.state = stateTop
+		default:
+			p.pop()
+			if p.peek() != "=" {
+				b, err := p.popNestedBlock(tok, pos)
+				if err != nil {
+					p.err = err
+					return p.err
+				}
+				p.curTerraform.Blocks = append(p.curTerraform.Blocks, b)
+				return nil
+			}
+			p.pop()
+			if _, exists := p.curTerraform.Attributes[tok]; exists {
+				p.err = fmt.Errorf("%v: Duplicated attribute %#q", pos, tok)
+				return p.err
+			}
+			if p.curTerraform.Attributes == nil {
+				p.curTerraform.Attributes, p.curTerraform.AttributesPos = make(map[string]string), make(map[string]Pos)
+			}
+			p.curTerraform.Attributes[tok] = unquoteString(p.popExpression())
+			p.curTerraform.AttributesPos[tok] = pos
+		}
+	case stateRequiredProviders:
+		return p.parseRequiredProvider()
+	}
+	return nil
+}
+
+// parses a single entry of required_providers block, which is either
+// 'name = "version"' or 'name = { source = ..., version = ..., configuration_aliases = [...] }'
+func (p *parser) parseRequiredProvider() error {
+	pos := p.peekPos()
+	name := p.pop()
+	if name == "}" {
+		p.state = stateTerraform
+		return nil
+	}
+	p.err = p.popToken("=")
+	if p.err != nil {
+		return p.err
+	}
+	if p.config.RequiredProviders == nil {
+		p.config.RequiredProviders = make(map[string]*RequiredProvider)
+	}
+	if _, exists := p.config.RequiredProviders[name]; exists {
+		p.err = fmt.Errorf("Duplicated required provider %#q", name)
+		return p.err
+	}
+	rp := &RequiredProvider{Name: name, Pos: pos}
+	p.config.RequiredProviders[name] = rp
+	if p.peek() != "{" {
+		rp.Version = p.pop()
+		return nil
+	}
+	p.pop()
+	for {
+		if p.i >= len(p.data) {
+			p.err = fmt.Errorf("Did not find the closing curly brace when parsing required provider %v", name)
+			return p.err
+		}
+		attr := p.pop()
+		switch attr {
+		case "}":
+			return nil
+		case ",":
+			continue
+		}
+		p.err = p.popToken("=")
+		if p.err != nil {
+			return p.err
+		}
+		switch attr {
+		case "source":
+			rp.Source = p.pop()
+		case "version":
+			rp.Version = p.pop()
+		case "configuration_aliases":
+			rp.ConfigurationAliases, p.err = p.popList()
+			if p.err != nil {
+				return p.err
+			}
+		default:
+			p.pop()
+		}
+	}
+}
+
+// parser for locals block, each local value is declared as separate object
+func (p *parser) parseLocals() error {
+	pos := p.peekPos()
+	name := p.pop()
+	if name == "}" {
+		p.state = stateTop
+		return nil
+	}
+	p.err = p.popToken("=")
+	if p.err != nil {
+		return p.err
+	}
+	expr := p.popExpression()
+	if p.err = p.declare("local."+name, pos, expr); p.err != nil {
+		return p.err
+	}
+	if p.config.locals == nil {
+		p.config.locals = make(map[string]string)
+	}
+	p.config.locals[name] = expr
+	return nil
+}
+
+func isMetaBlock(blockType string) bool {
+	switch blockType {
+	case "moved", "import", "removed", "check":
+		return true
+	}
+	return false
+}
+
+// parseMetaBlock parses 'moved', 'import', 'removed' and 'check' blocks with given header
+func (p *parser) parseMetaBlock(header []string, pos Pos) error {
+	labels := 0
+	if header[0] == "check" {
+		labels = 1
+	}
+	if len(header) != labels+1 {
+		return fmt.Errorf("%v: Unexpected number of labels of %#q block", pos, header[0])
+	}
+	// required returns error if one of attributes is not set
+	required := func(attrs map[string]string, names ...string) error {
+		for _, name := range names {
+			if attrs[name] == "" {
+				return fmt.Errorf("%v: Attribute %#q is required in %#q block", pos, name, header[0])
+			}
+		}
+		return nil
+	}
+
+	switch header[0] {
+	case "moved":
+		attrs, _, err := p.popAttributes(nil)
+		if err != nil {
+			return err
+		}
+		if err := required(attrs, "from", "to"); err != nil {
+			return err
+		}
+		p.config.Moved = append(p.config.Moved, &Moved{attrs["from"], attrs["to"], pos})
+	case "import":
+		attrs, _, err := p.popAttributes(nil)
+		if err != nil {
+			return err
+		}
+		if err := required(attrs, "to", "id"); err != nil {
+			return err
+		}
+		p.config.Imports = append(p.config.Imports, &Import{attrs["to"], unquoteString(attrs["id"]), attrs["provider"], attrs["for_each"], pos})
+	case "removed":
+		r := &Removed{Destroy: true, Pos: pos}
+		attrs, _, err := p.popAttributes(func(nested []string, _ Pos) error {
+			if len(nested) != 1 || nested[0] != "lifecycle" {
+				return p.skipBlock()
+			}
+			lifecycle, _, err := p.popAttributes(nil)
+			r.Destroy = lifecycle["destroy"] != "false"
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if err := required(attrs, "from"); err != nil {
+			return err
+		}
+		r.From = attrs["from"]
+		p.config.Removed = append(p.config.Removed, r)
+	case "check":
+		check := &Check{Name: header[1], Pos: pos}
+		_, _, err := p.popAttributes(func(nested []string, nestedPos Pos) error {
+			if len(nested) != 1 || nested[0] != "assert" {
+				return p.skipBlock()
+			}
+			attrs, _, err := p.popAttributes(nil)
+			check.Assertions = append(check.Assertions, &Assertion{attrs["condition"], unquoteString(attrs["error_message"]), nestedPos})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if p.config.Checks == nil {
+			p.config.Checks = make(map[string]*Check)
+		}
+		if _, exists := p.config.Checks[check.Name]; exists {
+			return fmt.Errorf("Duplicated check %#q", check.Name)
+		}
+		p.config.Checks[check.Name] = check
+	}
+	return nil
+}
+
+// popAttributes reads block body from the opening curly brace till the closing one and returns
+// its attributes as they are written along with their positions. Nested blocks are passed to
+// nested along with their header and position, nested must read the block from its opening
+// curly brace. Nested blocks are skipped if nested is nil
+func (p *parser) popAttributes(nested func(header []string, pos Pos) error) (map[string]string, map[string]Pos, error) {
+	if err := p.popToken("{"); err != nil {
+		return nil, nil, err
+	}
+	return p.popBody(true, nested)
+}
+
+// popBody reads attributes and nested blocks as popAttributes does, till the closing curly brace
+// if braced is set, or till the end of data otherwise. Attributes may be separated with commas,
+// as in object expressions
+func (p *parser) popBody(braced bool, nested func(header []string, pos Pos) error) (map[string]string, map[string]Pos, error) {
+	attrs := make(map[string]string)
+	attrsPos := make(map[string]Pos)
+	var header []string
+	var headerPos Pos
+	for {
+		pos := p.peekPos()
+		switch tok := p.peek(); {
+		case tok == "" && !braced && len(header) == 0:
+			return attrs, attrsPos, nil
+		case tok == "":
+			p.err = fmt.Errorf("Unable to find closing brace for block")
+			return nil, nil, p.err
+		case tok == "}" && len(header) == 0 && braced:
+			p.pop()
+			return attrs, attrsPos, nil
+		case tok == "," && len(header) == 0:
+			p.pop()
+		case tok == "{":
+			var err error
+			if nested != nil {
+				err = nested(header, headerPos)
+			} else {
+				err = p.skipBlock()
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			header = nil
+		case tok == "}" || tok == "=":
+			p.err = fmt.Errorf("%v: Unexpected %#q after %#q", pos, tok, strings.Join(header, " "))
+			return nil, nil, p.err
+		case len(header) > 0:
+			header = append(header, p.pop())
+		default:
+			p.pop()
+			if p.peek() != "=" {
+				header, headerPos = []string{tok}, pos
+				continue
+			}
+			p.pop()
+			if _, exists := attrs[tok]; exists {
+				p.err = fmt.Errorf("Duplicated attribute %#q", tok)
+				return nil, nil, p.err
+			}
+			attrs[tok] = p.popExpression()
+			attrsPos[tok] = pos
+		}
+	}
+}
+
+// popBlock reads generic block with given header from its opening curly brace
+func (p *parser) popBlock(header []string, pos Pos) (*Block, error) {
+	if len(header) == 0 {
+		p.err = fmt.Errorf("%v: Block type expected before %#q", pos, "{")
+		return nil, p.err
+	}
+	b := &Block{Type: header[0], Labels: header[1:], Pos: pos}
+	if err := p.popToken("{"); err != nil {
+		return nil, err
+	}
+	if err := p.popBlockBody(b, true); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// popBlockBody reads attributes and nested blocks of b, see popBody
+func (p *parser) popBlockBody(b *Block, braced bool) error {
+	attrs, attrsPos, err := p.popBody(braced, func(nested []string, nestedPos Pos) error {
+		child, err := p.popBlock(nested, nestedPos)
+		if err == nil {
+			b.Blocks = append(b.Blocks, child)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(attrs) > 0 {
+		b.Attributes, b.AttributesPos, b.exprs = make(map[string]string), attrsPos, attrs
+		for name, expr := range attrs {
+			b.Attributes[name] = unquoteString(expr)
+		}
+	}
+	return nil
+}
+
+// popNestedBlock reads labels of a nested block, which type has already been read, and the block
+func (p *parser) popNestedBlock(blockType string, pos Pos) (*Block, error) {
+	header := []string{blockType}
+	for {
+		switch tok := p.peek(); tok {
+		case "{":
+			return p.popBlock(header, pos)
+		case "", "}", "=":
+			p.err = fmt.Errorf("%v: Unexpected %#q in header of block %#q", p.pos(), tok, blockType)
+			return nil, p.err
+		default:
+			header = append(header, p.pop())
+		}
+	}
+}
+
+// declareBlock declares an object for top level block with given header (block type and labels)
+// and body. Blocks which can not be referenced are ignored
+func (p *parser) declareBlock(header []string, pos Pos, body string) error {
+	var address string
+	switch {
+	case len(header) == 3 && header[0] == "resource":
+		address = header[1] + "." + header[2]
+	case len(header) == 3 && header[0] == "data":
+		address = "data." + header[1] + "." + header[2]
+	case len(header) == 2 && header[0] == "variable":
+		address = "var." + header[1]
+	case len(header) == 2 && header[0] == "output":
+		address = "output." + header[1]
+	default:
+		return nil
+	}
+	return p.declare(address, pos, body)
+}
+
+// declare adds an object to config with all references found in expr
+func (p *parser) declare(address string, pos Pos, expr string) error {
+	if p.config.Objects == nil {
+		p.config.Objects = make(map[string]*Object)
+	}
+	if _, exists := p.config.Objects[address]; exists {
+		return fmt.Errorf("Duplicated declaration of %#q", address)
 	}
+	p.config.Objects[address] = &Object{address, findReferences(expr), pos}
 	return nil
 }
@@ -0,0 +1,1169 @@
+package tfparser
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// evalFunction is a built-in function, its arguments are known values
+type evalFunction func(args []Value) (Value, error)
+
+// impureFunctions are functions with results known only to terraform, their calls are Unknown
+var impureFunctions = map[string]bool{
+	"uuid": true, "uuidv5": true, "timestamp": true, "timeadd": true, "plantimestamp": true,
+	"bcrypt": true, "file": true, "filebase64": true, "fileexists": true, "fileset": true,
+	"filemd5": true, "filesha1": true, "filesha256": true, "filesha512": true,
+	"filebase64sha256": true, "filebase64sha512": true, "templatefile": true, "abspath": true,
+	"pathexpand": true, "sensitive": true, "nonsensitive": true,
+}
+
+// evalFunctions are pure built-in functions of terraform
+var evalFunctions map[string]evalFunction
+
+func init() {
+	evalFunctions = map[string]evalFunction{
+		// string functions
+		"chomp":       stringFunc(func(s string) Value { return strings.TrimRight(s, "\r\n") }),
+		"lower":       stringFunc(func(s string) Value { return strings.ToLower(s) }),
+		"upper":       stringFunc(func(s string) Value { return strings.ToUpper(s) }),
+		"title":       stringFunc(titleString),
+		"trimspace":   stringFunc(func(s string) Value { return strings.TrimSpace(s) }),
+		"strrev":      stringFunc(reverseString),
+		"startswith":  stringsFunc(2, func(s []string) (Value, error) { return strings.HasPrefix(s[0], s[1]), nil }),
+		"endswith":    stringsFunc(2, func(s []string) (Value, error) { return strings.HasSuffix(s[0], s[1]), nil }),
+		"strcontains": stringsFunc(2, func(s []string) (Value, error) { return strings.Contains(s[0], s[1]), nil }),
+		"trim":        stringsFunc(2, func(s []string) (Value, error) { return strings.Trim(s[0], s[1]), nil }),
+		"trimprefix":  stringsFunc(2, func(s []string) (Value, error) { return strings.TrimPrefix(s[0], s[1]), nil }),
+		"trimsuffix":  stringsFunc(2, func(s []string) (Value, error) { return strings.TrimSuffix(s[0], s[1]), nil }),
+		"split":       stringsFunc(2, funcSplit),
+		"replace":     stringsFunc(3, funcReplace),
+		"regex":       stringsFunc(2, funcRegex),
+		"regexall":    stringsFunc(2, funcRegexAll),
+		"join":        funcJoin,
+		"substr":      funcSubstr,
+		"indent":      funcIndent,
+		"format":      funcFormat,
+
+		// collection functions
+		"length":       funcLength,
+		"concat":       funcConcat,
+		"contains":     funcContains,
+		"distinct":     funcDistinct,
+		"element":      funcElement,
+		"flatten":      funcFlatten,
+		"index":        funcIndex,
+		"keys":         funcKeys,
+		"values":       funcValues,
+		"lookup":       funcLookup,
+		"merge":        funcMerge,
+		"range":        funcRange,
+		"reverse":      funcReverse,
+		"slice":        funcSlice,
+		"sort":         funcSort,
+		"zipmap":       funcZipmap,
+		"coalesce":     funcCoalesce,
+		"coalescelist": funcCoalesceList,
+		"compact":      funcCompact,
+		"one":          funcOne,
+		"sum":          funcSum,
+		"alltrue":      funcAllTrue,
+		"anytrue":      funcAnyTrue,
+		"chunklist":    funcChunklist,
+		"setunion":     funcSetUnion,
+		"toset":        funcToSet,
+		"tolist":       funcToList,
+		"tomap":        funcToMap,
+		"tostring":     funcToString,
+		"tonumber":     funcToNumber,
+		"tobool":       funcToBool,
+
+		// encoding functions
+		"jsonencode":   funcJSONEncode,
+		"jsondecode":   stringsFunc(1, funcJSONDecode),
+		"base64encode": stringFunc(func(s string) Value { return base64.StdEncoding.EncodeToString([]byte(s)) }),
+		"base64decode": stringsFunc(1, funcBase64Decode),
+		"urlencode":    stringFunc(func(s string) Value { return url.QueryEscape(s) }),
+		"csvdecode":    stringsFunc(1, funcCSVDecode),
+
+		// numeric functions
+		"abs":      numberFunc(math.Abs),
+		"ceil":     numberFunc(math.Ceil),
+		"floor":    numberFunc(math.Floor),
+		"signum":   numberFunc(signum),
+		"log":      numbersFunc(2, func(n []float64) Value { return math.Log(n[0]) / math.Log(n[1]) }),
+		"pow":      numbersFunc(2, func(n []float64) Value { return math.Pow(n[0], n[1]) }),
+		"max":      numbersFunc(-1, func(n []float64) Value { return extremum(n, math.Max) }),
+		"min":      numbersFunc(-1, func(n []float64) Value { return extremum(n, math.Min) }),
+		"parseint": funcParseInt,
+
+		// hash functions
+		"md5":          hashFunc(md5.New, hex.EncodeToString),
+		"sha1":         hashFunc(sha1.New, hex.EncodeToString),
+		"sha256":       hashFunc(sha256.New, hex.EncodeToString),
+		"sha512":       hashFunc(sha512.New, hex.EncodeToString),
+		"base64sha256": hashFunc(sha256.New, base64.StdEncoding.EncodeToString),
+		"base64sha512": hashFunc(sha512.New, base64.StdEncoding.EncodeToString),
+
+		// ip network functions
+		"cidrhost":    funcCIDRHost,
+		"cidrnetmask": stringsFunc(1, funcCIDRNetmask),
+		"cidrsubnet":  funcCIDRSubnet,
+		"cidrsubnets": funcCIDRSubnets,
+	}
+}
+
+func checkArgs(args []Value, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("%v arguments expected, found %v", n, len(args))
+	}
+	return nil
+}
+
+// stringFunc makes function of a single string argument
+func stringFunc(f func(string) Value) evalFunction {
+	return stringsFunc(1, func(s []string) (Value, error) { return f(s[0]), nil })
+}
+
+// stringsFunc makes function of n string arguments
+func stringsFunc(n int, f func([]string) (Value, error)) evalFunction {
+	return func(args []Value) (Value, error) {
+		if err := checkArgs(args, n); err != nil {
+			return nil, err
+		}
+		s := make([]string, n)
+		for i, arg := range args {
+			var err error
+			if s[i], err = toString(arg); err != nil {
+				return nil, err
+			}
+		}
+		return f(s)
+	}
+}
+
+func numberFunc(f func(float64) float64) evalFunction {
+	return numbersFunc(1, func(n []float64) Value { return f(n[0]) })
+}
+
+// numbersFunc makes function of n number arguments, or of at least one argument if n is negative
+func numbersFunc(n int, f func([]float64) Value) evalFunction {
+	return func(args []Value) (Value, error) {
+		if n < 0 && len(args) == 0 {
+			return nil, fmt.Errorf("at least one argument expected")
+		}
+		if n >= 0 {
+			if err := checkArgs(args, n); err != nil {
+				return nil, err
+			}
+		}
+		nums := make([]float64, len(args))
+		for i, arg := range args {
+			var err error
+			if nums[i], err = toNumber(arg); err != nil {
+				return nil, err
+			}
+		}
+		return f(nums), nil
+	}
+}
+
+func hashFunc(h func() hash.Hash, encode func([]byte) string) evalFunction {
+	return stringFunc(func(s string) Value {
+		sum := h()
+		sum.Write([]byte(s))
+		return encode(sum.Sum(nil))
+	})
+}
+
+// titleString upper cases the first letter of each word of s, words are separated by anything
+// but letters, digits and underscores
+func titleString(s string) Value {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		start := !unicode.IsLetter(prev) && !unicode.IsDigit(prev) && prev != '_'
+		prev = r
+		if start {
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, s)
+}
+
+func reverseString(s string) Value {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+func signum(f float64) float64 {
+	switch {
+	case f > 0:
+		return 1
+	case f < 0:
+		return -1
+	}
+	return 0
+}
+
+func extremum(nums []float64, f func(a, b float64) float64) float64 {
+	result := nums[0]
+	for _, n := range nums[1:] {
+		result = f(result, n)
+	}
+	return result
+}
+
+func stringList(items []string) []Value {
+	list := make([]Value, len(items))
+	for i, item := range items {
+		list[i] = item
+	}
+	return list
+}
+
+func funcSplit(s []string) (Value, error) {
+	return stringList(strings.Split(s[1], s[0])), nil
+}
+
+// funcReplace replaces substring, or regular expression if it is written in slashes, e.g. '/[0-9]+/'
+func funcReplace(s []string) (Value, error) {
+	if len(s[1]) > 1 && strings.HasPrefix(s[1], "/") && strings.HasSuffix(s[1], "/") {
+		re, err := regexp.Compile(s[1][1 : len(s[1])-1])
+		if err != nil {
+			return nil, err
+		}
+		return re.ReplaceAllString(s[0], s[2]), nil
+	}
+	return strings.ReplaceAll(s[0], s[1], s[2]), nil
+}
+
+// regexMatch returns the match of re as terraform does: string without capture groups, list for
+// unnamed groups and object for named ones
+func regexMatch(re *regexp.Regexp, m []string) Value {
+	if re.NumSubexp() == 0 {
+		return m[0]
+	}
+	names := re.SubexpNames()
+	if names[1] != "" {
+		obj := make(map[string]Value)
+		for i, name := range names[1:] {
+			obj[name] = m[i+1]
+		}
+		return obj
+	}
+	return stringList(m[1:])
+}
+
+func funcRegex(s []string) (Value, error) {
+	re, err := regexp.Compile(s[0])
+	if err != nil {
+		return nil, err
+	}
+	m := re.FindStringSubmatch(s[1])
+	if m == nil {
+		return nil, fmt.Errorf("pattern did not match any part of the given string")
+	}
+	return regexMatch(re, m), nil
+}
+
+func funcRegexAll(s []string) (Value, error) {
+	re, err := regexp.Compile(s[0])
+	if err != nil {
+		return nil, err
+	}
+	list := []Value{}
+	for _, m := range re.FindAllStringSubmatch(s[1], -1) {
+		list = append(list, regexMatch(re, m))
+	}
+	return list, nil
+}
+
+func funcJoin(args []Value) (Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("at least 2 arguments expected, found %v", len(args))
+	}
+	sep, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	var items []string
+	for _, arg := range args[1:] {
+		list, err := toList(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list {
+			s, err := toString(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, s)
+		}
+	}
+	return strings.Join(items, sep), nil
+}
+
+func funcSubstr(args []Value) (Value, error) {
+	if err := checkArgs(args, 3); err != nil {
+		return nil, err
+	}
+	s, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	offset, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	length, err := toInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	if offset < 0 {
+		offset += len(runes)
+	}
+	if offset < 0 || offset > len(runes) {
+		return nil, fmt.Errorf("offset %v is out of range", offset)
+	}
+	end := offset + length
+	if length < 0 || end > len(runes) {
+		end = len(runes)
+	}
+	return string(runes[offset:end]), nil
+}
+
+func funcIndent(args []Value) (Value, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	n, err := toInt(args[0])
+	if err != nil {
+		return nil, err
+	}
+	s, err := toString(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(s, "\n", "\n"+strings.Repeat(" ", n)), nil
+}
+
+var formatVerbRe = regexp.MustCompile(`%(%|[-+ #0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z])`)
+
+// funcFormat formats args like terraform's format, verbs are those of fmt: %s, %d, %f, %t, %q
+// and %v. %d requires a whole number, %v is formatted as value is written in configuration
+func funcFormat(args []Value) (Value, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("at least one argument expected")
+	}
+	spec, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	args = args[1:]
+	var formatErr error
+	result := formatVerbRe.ReplaceAllStringFunc(spec, func(verb string) string {
+		if verb == "%%" || formatErr != nil {
+			return "%"
+		}
+		if len(args) == 0 {
+			formatErr = fmt.Errorf("not enough arguments for %#q", verb)
+			return ""
+		}
+		arg := args[0]
+		args = args[1:]
+		switch verb[len(verb)-1] {
+		case 's', 'q':
+			s, err := toString(arg)
+			formatErr = err
+			return fmt.Sprintf(verb, s)
+		case 'd':
+			n, err := toInt(arg)
+			formatErr = err
+			return fmt.Sprintf(verb, n)
+		case 'f', 'e', 'g':
+			n, err := toNumber(arg)
+			formatErr = err
+			return fmt.Sprintf(verb, n)
+		case 't':
+			b, err := toBool(arg)
+			formatErr = err
+			return fmt.Sprintf(verb, b)
+		case 'v':
+			if s, ok := arg.(string); ok {
+				return s
+			}
+			return FormatValue(arg)
+		}
+		formatErr = fmt.Errorf("unsupported verb %#q", verb)
+		return ""
+	})
+	if formatErr != nil {
+		return nil, formatErr
+	}
+	return result, nil
+}
+
+func funcLength(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case string:
+		return float64(utf8.RuneCountInString(v)), nil
+	case []Value:
+		return float64(len(v)), nil
+	case map[string]Value:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("collection or string required, found %v", typeName(args[0]))
+}
+
+func funcConcat(args []Value) (Value, error) {
+	result := []Value{}
+	for _, arg := range args {
+		list, err := toList(arg)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, list...)
+	}
+	return result, nil
+}
+
+func funcContains(args []Value) (Value, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return indexOf(list, args[1]) >= 0, nil
+}
+
+func indexOf(list []Value, v Value) int {
+	for i, item := range list {
+		if valuesEqual(item, v) {
+			return i
+		}
+	}
+	return -1
+}
+
+func funcDistinct(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return distinct(list), nil
+}
+
+func distinct(list []Value) []Value {
+	result := []Value{}
+	for _, item := range list {
+		if indexOf(result, item) < 0 {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func funcElement(args []Value) (Value, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	i, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("cannot use element function with an empty list")
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("cannot use element function with a negative index")
+	}
+	return list[i%len(list)], nil
+}
+
+func funcFlatten(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return flatten(list), nil
+}
+
+func flatten(list []Value) []Value {
+	result := []Value{}
+	for _, item := range list {
+		if nested, ok := item.([]Value); ok {
+			result = append(result, flatten(nested)...)
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func funcIndex(args []Value) (Value, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	i := indexOf(list, args[1])
+	if i < 0 {
+		return nil, fmt.Errorf("item not found")
+	}
+	return float64(i), nil
+}
+
+func funcKeys(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	m, err := toMap(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return stringList(sortedValueKeys(m)), nil
+}
+
+func funcValues(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	m, err := toMap(args[0])
+	if err != nil {
+		return nil, err
+	}
+	list := []Value{}
+	for _, k := range sortedValueKeys(m) {
+		list = append(list, m[k])
+	}
+	return list, nil
+}
+
+func funcLookup(args []Value) (Value, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("2 or 3 arguments expected, found %v", len(args))
+	}
+	m, err := toMap(args[0])
+	if err != nil {
+		return nil, err
+	}
+	key, err := toString(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if v, exists := m[key]; exists {
+		return v, nil
+	}
+	if len(args) == 3 {
+		return args[2], nil
+	}
+	return nil, fmt.Errorf("key %#q does not exist", key)
+}
+
+func funcMerge(args []Value) (Value, error) {
+	result := make(map[string]Value)
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		m, err := toMap(arg)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range m {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+func funcRange(args []Value) (Value, error) {
+	if len(args) == 0 || len(args) > 3 {
+		return nil, fmt.Errorf("1 to 3 arguments expected, found %v", len(args))
+	}
+	nums := make([]float64, len(args))
+	for i, arg := range args {
+		var err error
+		if nums[i], err = toNumber(arg); err != nil {
+			return nil, err
+		}
+	}
+	start, limit, step := 0.0, nums[0], 1.0
+	if len(nums) > 1 {
+		start, limit = nums[0], nums[1]
+	}
+	if len(nums) > 2 {
+		step = nums[2]
+	} else if limit < start {
+		step = -1
+	}
+	if step == 0 || (step > 0) != (limit >= start) && limit != start {
+		return nil, fmt.Errorf("step %v does not lead from %v to %v", formatNumber(step), formatNumber(start), formatNumber(limit))
+	}
+	list := []Value{}
+	for n := start; (step > 0 && n < limit) || (step < 0 && n > limit); n += step {
+		if len(list) >= 1024 {
+			return nil, fmt.Errorf("more than 1024 numbers requested")
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+func funcReverse(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Value, len(list))
+	for i, item := range list {
+		result[len(list)-1-i] = item
+	}
+	return result, nil
+}
+
+func funcSlice(args []Value) (Value, error) {
+	if err := checkArgs(args, 3); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	start, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	end, err := toInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || end > len(list) || start > end {
+		return nil, fmt.Errorf("invalid range [%v:%v] of list with %v elements", start, end, len(list))
+	}
+	return append([]Value{}, list[start:end]...), nil
+}
+
+func funcSort(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	items := make([]string, len(list))
+	for i, item := range list {
+		if items[i], err = toString(item); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(items)
+	return stringList(items), nil
+}
+
+func funcZipmap(args []Value) (Value, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	keys, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	values, err := toList(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("number of keys (%v) does not match number of values (%v)", len(keys), len(values))
+	}
+	result := make(map[string]Value)
+	for i, key := range keys {
+		k, err := toString(key)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = values[i]
+	}
+	return result, nil
+}
+
+func funcCoalesce(args []Value) (Value, error) {
+	for _, arg := range args {
+		if arg != nil && arg != "" {
+			return arg, nil
+		}
+	}
+	return nil, fmt.Errorf("no non-null, non-empty-string arguments")
+}
+
+func funcCoalesceList(args []Value) (Value, error) {
+	for _, arg := range args {
+		list, err := toList(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) > 0 {
+			return list, nil
+		}
+	}
+	return []Value{}, nil
+}
+
+func funcCompact(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	result := []Value{}
+	for _, item := range list {
+		if item != nil && item != "" {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func funcOne(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch len(list) {
+	case 0:
+		return nil, nil
+	case 1:
+		return list[0], nil
+	}
+	return nil, fmt.Errorf("list must have no more than one element, found %v", len(list))
+}
+
+func funcSum(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("cannot sum an empty list")
+	}
+	sum := 0.0
+	for _, item := range list {
+		n, err := toNumber(item)
+		if err != nil {
+			return nil, err
+		}
+		sum += n
+	}
+	return sum, nil
+}
+
+// boolsFunc makes function of a list of bools
+func boolsFunc(all bool) evalFunction {
+	return func(args []Value) (Value, error) {
+		if err := checkArgs(args, 1); err != nil {
+			return nil, err
+		}
+		list, err := toList(args[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list {
+			b, err := toBool(item)
+			if err != nil {
+				return nil, err
+			}
+			if b != all {
+				return !all, nil
+			}
+		}
+		return all, nil
+	}
+}
+
+var (
+	funcAllTrue = boolsFunc(true)
+	funcAnyTrue = boolsFunc(false)
+)
+
+func funcChunklist(args []Value) (Value, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	size, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("chunk size must not be negative")
+	}
+	if size == 0 {
+		return []Value{list}, nil
+	}
+	result := []Value{}
+	for i := 0; i < len(list); i += size {
+		end := i + size
+		if end > len(list) {
+			end = len(list)
+		}
+		result = append(result, append([]Value{}, list[i:end]...))
+	}
+	return result, nil
+}
+
+func funcSetUnion(args []Value) (Value, error) {
+	all, err := funcConcat(args)
+	if err != nil {
+		return nil, err
+	}
+	return funcToSet([]Value{all})
+}
+
+// funcToSet removes duplicates and sorts strings and numbers, as terraform sets are ordered so
+func funcToSet(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+	set := distinct(list)
+	sort.SliceStable(set, func(i, j int) bool {
+		a, aNum := set[i].(float64)
+		b, bNum := set[j].(float64)
+		if aNum && bNum {
+			return a < b
+		}
+		x, xStr := set[i].(string)
+		y, yStr := set[j].(string)
+		return xStr && yStr && x < y
+	})
+	return set, nil
+}
+
+func funcToList(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	return toList(args[0])
+}
+
+func funcToMap(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	return toMap(args[0])
+}
+
+func funcToString(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	if args[0] == nil {
+		return nil, nil
+	}
+	return toString(args[0])
+}
+
+func funcToNumber(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	if args[0] == nil {
+		return nil, nil
+	}
+	return toNumber(args[0])
+}
+
+func funcToBool(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	if args[0] == nil {
+		return nil, nil
+	}
+	return toBool(args[0])
+}
+
+func funcJSONEncode(args []Value) (Value, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(args[0]); err != nil {
+		return nil, err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func funcJSONDecode(s []string) (Value, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s[0]), &v); err != nil {
+		return nil, err
+	}
+	return fromJSON(v), nil
+}
+
+// fromJSON converts decoded JSON value to Value
+func fromJSON(v interface{}) Value {
+	switch v := v.(type) {
+	case []interface{}:
+		list := make([]Value, len(v))
+		for i, item := range v {
+			list[i] = fromJSON(item)
+		}
+		return list
+	case map[string]interface{}:
+		obj := make(map[string]Value, len(v))
+		for k, item := range v {
+			obj[k] = fromJSON(item)
+		}
+		return obj
+	}
+	return v
+}
+
+func funcBase64Decode(s []string) (Value, error) {
+	b, err := base64.StdEncoding.DecodeString(s[0])
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(b) {
+		return nil, fmt.Errorf("decoded result is not valid UTF-8")
+	}
+	return string(b), nil
+}
+
+func funcCSVDecode(s []string) (Value, error) {
+	records, err := csv.NewReader(strings.NewReader(s[0])).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header line")
+	}
+	result := []Value{}
+	for _, record := range records[1:] {
+		row := make(map[string]Value)
+		for i, name := range records[0] {
+			row[name] = record[i]
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+func funcParseInt(args []Value) (Value, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	s, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	base, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if base < 2 || base > 62 {
+		return nil, fmt.Errorf("base must be a whole number between 2 and 62 inclusive")
+	}
+	n, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, fmt.Errorf("cannot parse %#q as a base %v integer", s, base)
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f, nil
+}
+
+// parseCIDR parses network prefix like '10.0.0.0/16' to network address and prefix length
+func parseCIDR(s string) (*big.Int, int, int, error) {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("invalid CIDR expression: %v", err)
+	}
+	ones, bits := network.Mask.Size()
+	ip := network.IP
+	if bits == 32 {
+		ip = ip.To4()
+	}
+	return new(big.Int).SetBytes(ip), ones, bits, nil
+}
+
+// formatIP formats address n of an address family of bits
+func formatIP(n *big.Int, bits int) string {
+	b := n.Bytes()
+	ip := make(net.IP, bits/8)
+	copy(ip[len(ip)-len(b):], b)
+	return ip.String()
+}
+
+// subnet returns address of subnet num of the network with prefix length ones extended by newbits
+func subnet(network *big.Int, ones, bits, newbits int, num *big.Int) (*big.Int, error) {
+	if newbits < 0 || ones+newbits > bits {
+		return nil, fmt.Errorf("insufficient address space to extend prefix of %v by %v", ones, newbits)
+	}
+	if num.Sign() < 0 || num.BitLen() > newbits {
+		return nil, fmt.Errorf("prefix extension of %v does not accommodate a subnet numbered %v", newbits, num)
+	}
+	return new(big.Int).Or(network, new(big.Int).Lsh(num, uint(bits-ones-newbits))), nil
+}
+
+func funcCIDRSubnet(args []Value) (Value, error) {
+	if err := checkArgs(args, 3); err != nil {
+		return nil, err
+	}
+	prefix, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	newbits, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	num, err := toInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+	network, ones, bits, err := parseCIDR(prefix)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := subnet(network, ones, bits, newbits, big.NewInt(int64(num)))
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("%v/%v", formatIP(addr, bits), ones+newbits), nil
+}
+
+func funcCIDRSubnets(args []Value) (Value, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("at least one argument expected")
+	}
+	prefix, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	network, ones, bits, err := parseCIDR(prefix)
+	if err != nil {
+		return nil, err
+	}
+	// each subnet starts right after the previous one, aligned to its own size
+	next := new(big.Int).Set(network)
+	end := new(big.Int).Add(network, new(big.Int).Lsh(big.NewInt(1), uint(bits-ones)))
+	result := []Value{}
+	for _, arg := range args[1:] {
+		newbits, err := toInt(arg)
+		if err != nil {
+			return nil, err
+		}
+		if newbits < 1 || ones+newbits > bits {
+			return nil, fmt.Errorf("invalid new bits %v for prefix length %v", newbits, ones)
+		}
+		size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones-newbits))
+		rem := new(big.Int).Mod(new(big.Int).Sub(next, network), size)
+		if rem.Sign() != 0 {
+			next.Add(next, new(big.Int).Sub(size, rem))
+		}
+		if new(big.Int).Add(next, size).Cmp(end) > 0 {
+			return nil, fmt.Errorf("not enough remaining address space for a subnet with a prefix of %v bits", ones+newbits)
+		}
+		result = append(result, fmt.Sprintf("%v/%v", formatIP(next, bits), ones+newbits))
+		next.Add(next, size)
+	}
+	return result, nil
+}
+
+func funcCIDRHost(args []Value) (Value, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	prefix, err := toString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	num, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	network, ones, bits, err := parseCIDR(prefix)
+	if err != nil {
+		return nil, err
+	}
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	host := big.NewInt(int64(num))
+	if num < 0 {
+		host.Add(host, size)
+	}
+	if host.Sign() < 0 || host.Cmp(size) >= 0 {
+		return nil, fmt.Errorf("prefix of %v does not accommodate a host numbered %v", ones, num)
+	}
+	return formatIP(new(big.Int).Add(network, host), bits), nil
+}
+
+func funcCIDRNetmask(s []string) (Value, error) {
+	_, network, err := net.ParseCIDR(s[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR expression: %v", err)
+	}
+	if len(network.IP.To4()) != net.IPv4len {
+		return nil, fmt.Errorf("only IPv4 networks have netmasks")
+	}
+	return net.IP(network.Mask).String(), nil
+}
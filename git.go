@@ -0,0 +1,113 @@
+package tfparser
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ParseTar parses terraform config in all *.tf files of directory dirname in tar archive read
+// from r, e.g. output of 'git archive'. As in ParseDir, subdirectories are not read.
+// Positions refer to file names as they are in the archive
+func ParseTar(r io.Reader, dirname string) (*TFconfig, error) {
+	dirname = path.Clean(filepath.ToSlash(dirname))
+	var names []string
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read archive: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Dir(path.Clean(hdr.Name)) != dirname || !strings.HasSuffix(hdr.Name, ".tf") {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read %v from archive: %v", hdr.Name, err)
+		}
+		names = append(names, hdr.Name)
+		files[hdr.Name] = data
+	}
+	return parseFiles(names, func(name string) ([]byte, error) {
+		return files[name], nil
+	}, func(name string) string { return name })
+}
+
+// ParseGitRevision parses terraform config in directory dirname of local git repository repo at
+// revision rev, e.g. a commit, branch or tag, without checking it out. dirname is relative to the
+// root of repository and must not refer outside of it, if it does not exist at rev, configuration
+// is empty. The revision is read with 'git archive', so git must be installed
+func ParseGitRevision(repo, rev, dirname string) (*TFconfig, error) {
+	config, _, err := parseGitRevision(repo, rev, dirname)
+	return config, err
+}
+
+// parseGitRevision is ParseGitRevision, which also tells if dirname exists at rev
+func parseGitRevision(repo, rev, dirname string) (*TFconfig, bool, error) {
+	slashed := filepath.ToSlash(dirname)
+	if path.IsAbs(slashed) || filepath.IsAbs(dirname) || containsString(strings.Split(slashed, "/"), "..") {
+		return nil, false, fmt.Errorf("Invalid directory %#q, path relative to the root of repository required", dirname)
+	}
+	dirname = path.Clean(slashed)
+	// revision starting with '-' would be read by git as an option
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return nil, false, fmt.Errorf("Invalid revision %#q", rev)
+	}
+	out, err := runGit(repo, "rev-parse", "--verify", "--quiet", rev+"^{tree}")
+	if err != nil {
+		return nil, false, fmt.Errorf("Unable to find revision %v: %v", rev, err)
+	}
+	tree := strings.TrimSpace(out.String())
+	args := []string{"archive", "--format=tar", tree}
+	if dirname != "." {
+		if _, err := runGit(repo, "rev-parse", "--verify", "--quiet", tree+":"+dirname); err != nil {
+			return &TFconfig{}, false, nil
+		}
+		args = append(args, "--", dirname)
+	}
+	out, err = runGit(repo, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("Unable to read %v at revision %v: %v", dirname, rev, err)
+	}
+	config, err := ParseTar(out, dirname)
+	return config, err == nil, err
+}
+
+// runGit runs git command with args in repository repo and returns its output
+func runGit(repo string, args ...string) (*bytes.Buffer, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v %v", err, strings.TrimSpace(stderr.String()))
+	}
+	return &stdout, nil
+}
+
+// DiffGitRevisions compares module calls of terraform config in directory dirname of local git
+// repository repo at revisions from and to, see Diff and ParseGitRevision. If dirname does not
+// exist at one of revisions, all module calls are added or removed, it must exist at least at one
+func DiffGitRevisions(repo, dirname, from, to string) ([]ConfigChange, error) {
+	a, existsFrom, err := parseGitRevision(repo, from, dirname)
+	if err != nil {
+		return nil, err
+	}
+	b, existsTo, err := parseGitRevision(repo, to, dirname)
+	if err != nil {
+		return nil, err
+	}
+	if !existsFrom && !existsTo {
+		return nil, fmt.Errorf("Directory %v exists neither at revision %v nor at %v", dirname, from, to)
+	}
+	return Diff(a, b), nil
+}
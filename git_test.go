@@ -0,0 +1,158 @@
+package tfparser
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, data string }{
+		{"main.tf", `module "root" { source = "./root" }`},
+		{"live/", ""},
+		{"live/main.tf", `module "vpc" { source = "../modules/vpc" }`},
+		{"live/README.md", `module "readme" {`},
+		{"live/nested/main.tf", `module "nested" { source = "./nested" }`},
+	} {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}
+		if f.data == "" {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for dir, module := range map[string]string{".": "root", "live": "vpc", "./live/": "vpc"} {
+		config, err := ParseTar(bytes.NewReader(buf.Bytes()), dir)
+		if err != nil {
+			t.Fatalf("ParseTar returned an error for %v, %v", dir, err)
+		}
+		if len(config.Modules) != 1 || config.Modules[module] == nil {
+			t.Errorf("Unexpected modules in %v: %v", dir, config.Modules)
+		}
+	}
+}
+
+func TestDiffGitRevisions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	git := gitRunner(t, repo)
+	write := func(data string) {
+		if err := os.MkdirAll(filepath.Join(repo, "live"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(repo, "live", "main.tf"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	write("module \"vpc\" {\n  source = \"./modules/vpc\"\n  cidr   = \"10.0.0.0/16\"\n}\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "first")
+	git("tag", "v1")
+	write("module \"vpc\" {\n  cidr = \"10.1.0.0/16\"\n  source = \"./modules/vpc\"\n}\n")
+	git("commit", "-q", "-a", "-m", "second")
+	// working tree is not read
+	write("module \"other\" {\n  source = \"./other\"\n}\n")
+
+	changes, err := DiffGitRevisions(repo, "live", "v1", "HEAD")
+	if err != nil {
+		t.Fatalf("DiffGitRevisions returned an error, %v", err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	expected := []string{"live/main.tf:2:3: module `vpc`: parameter `cidr` changed from `10.0.0.0/16` to `10.1.0.0/16`"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected changes %v, expected %v", got, expected)
+	}
+
+	if _, err := DiffGitRevisions(repo, "live", "unknown", "HEAD"); err == nil {
+		t.Error("DiffGitRevisions did not return an error for unknown revision")
+	}
+	// revisions must not be read as options of git
+	output := filepath.Join(t.TempDir(), "out.tar")
+	for _, rev := range []string{"--output=" + output, "--remote=" + repo, "-v"} {
+		if _, err := ParseGitRevision(repo, rev, "live"); err == nil {
+			t.Errorf("ParseGitRevision did not return an error for revision %#q", rev)
+		}
+	}
+	if _, err := os.Stat(output); err == nil {
+		t.Errorf("Revision was read as an option, %v is written", output)
+	}
+}
+
+func TestDiffGitRevisionsMissingDir(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	git := gitRunner(t, repo)
+	git("init", "-q")
+	if err := ioutil.WriteFile(filepath.Join(repo, "README.md"), []byte("readme\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "-A")
+	git("commit", "-q", "-m", "first")
+	git("tag", "v1")
+	if err := os.MkdirAll(filepath.Join(repo, "live"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(repo, "live", "main.tf"), []byte("module \"vpc\" {\n  source = \"./modules/vpc\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "-A")
+	git("commit", "-q", "-m", "second")
+
+	for _, c := range []struct {
+		from, to string
+		expected string
+	}{
+		{"v1", "HEAD", "live/main.tf:1:1: module `vpc` added"},
+		{"HEAD", "v1", "live/main.tf:1:1: module `vpc` removed"},
+	} {
+		changes, err := DiffGitRevisions(repo, "live", c.from, c.to)
+		if err != nil {
+			t.Fatalf("DiffGitRevisions returned an error, %v", err)
+		}
+		if len(changes) != 1 || changes[0].String() != c.expected {
+			t.Errorf("Unexpected changes from %v to %v: %v, expected %v", c.from, c.to, changes, c.expected)
+		}
+	}
+	if _, err := DiffGitRevisions(repo, "missing", "v1", "HEAD"); err == nil {
+		t.Error("DiffGitRevisions did not return an error for directory missing at both revisions")
+	}
+	for _, dir := range []string{"/etc", "../other", "live/../../other", ".."} {
+		if _, err := ParseGitRevision(repo, "HEAD", dir); err == nil {
+			t.Errorf("ParseGitRevision did not return an error for directory %#q", dir)
+		}
+	}
+}
+
+// gitRunner returns function running git commands in repo, which fails the test on errors
+func gitRunner(t *testing.T, repo string) func(args ...string) {
+	return func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package tfparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph is a dependency graph between objects of configuration (see Object).
+// Object A depends on object B if any expression of A refers to B, including depends_on
+type Graph struct {
+	dependencies map[string]map[string]bool // object address -> addresses it depends on
+	dependents   map[string]map[string]bool // object address -> addresses depending on it
+}
+
+// NewGraph builds dependency graph for config from its objects, i.e. modules, resources, data
+// sources, variables, locals and outputs, and references between them. References to objects
+// which are not declared in config (e.g. 'count.index', 'path.module' or variables declared
+// elsewhere) are ignored
+func NewGraph(config *TFconfig) *Graph {
+	g := &Graph{make(map[string]map[string]bool), make(map[string]map[string]bool)}
+	for address := range config.Objects {
+		g.dependencies[address] = make(map[string]bool)
+		g.dependents[address] = make(map[string]bool)
+	}
+	for address, obj := range config.Objects {
+		for _, ref := range obj.References {
+			dep := resolveReference(ref, config.Objects)
+			if dep == "" || dep == address {
+				continue
+			}
+			g.dependencies[address][dep] = true
+			g.dependents[dep][address] = true
+		}
+	}
+	return g
+}
+
+// resolveReference returns address of an object traversal ref refers to, or empty string
+func resolveReference(ref string, objects map[string]*Object) string {
+	parts := strings.Split(ref, ".")
+	n := 2
+	if parts[0] == "data" {
+		n = 3
+	}
+	if len(parts) < n {
+		return ""
+	}
+	address := strings.Join(parts[:n], ".")
+	if _, exists := objects[address]; !exists {
+		return ""
+	}
+	return address
+}
+
+// Nodes returns addresses of all objects in the graph, sorted
+func (g *Graph) Nodes() []string {
+	nodes := make(map[string]bool, len(g.dependencies))
+	for address := range g.dependencies {
+		nodes[address] = true
+	}
+	return sortedKeys(nodes)
+}
+
+// Dependencies returns addresses of objects address directly depends on, sorted
+func (g *Graph) Dependencies(address string) []string {
+	return sortedKeys(g.dependencies[address])
+}
+
+// Dependents returns addresses of objects which directly depend on address, sorted
+func (g *Graph) Dependents(address string) []string {
+	return sortedKeys(g.dependents[address])
+}
+
+// AllDependents returns addresses of all objects which depend on address directly or transitively,
+// i.e. everything which may be affected by a change of address. Result is sorted
+func (g *Graph) AllDependents(address string) []string {
+	visited := make(map[string]bool)
+	queue := []string{address}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for dep := range g.dependents[cur] {
+			if !visited[dep] && dep != address {
+				visited[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return sortedKeys(visited)
+}
+
+// TopologicalSort returns addresses of all objects ordered so that every object goes after
+// all objects it depends on. Of all objects ready to go next, the smallest address goes first.
+// An error is returned if the graph has cycles
+func (g *Graph) TopologicalSort() ([]string, error) {
+	pending := make(map[string]int)
+	var ready []string
+	for address, deps := range g.dependencies {
+		pending[address] = len(deps)
+		if len(deps) == 0 {
+			ready = append(ready, address)
+		}
+	}
+	sort.Strings(ready)
+	var sorted []string
+	for len(ready) > 0 {
+		cur := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, cur)
+		var next []string
+		for dep := range g.dependents[cur] {
+			pending[dep]--
+			if pending[dep] == 0 {
+				next = append(next, dep)
+			}
+		}
+		if len(next) > 0 {
+			ready = append(ready, next...)
+			sort.Strings(ready)
+		}
+	}
+	if len(sorted) != len(g.dependencies) {
+		cycles := g.Cycles()
+		return nil, fmt.Errorf("Dependency cycle found: %v", strings.Join(cycles[0], " -> "))
+	}
+	return sorted, nil
+}
+
+// Cycles returns all dependency cycles in the graph. Each cycle is a list of addresses, starting
+// with the smallest one, where every object depends on the next one and the last one depends on
+// the first one. Cycles are sorted by their first address
+func (g *Graph) Cycles() [][]string {
+	// Tarjan's strongly connected components algorithm, every component of more than
+	// one object contains at least one cycle
+	var (
+		index    = make(map[string]int)
+		lowlink  = make(map[string]int)
+		onStack  = make(map[string]bool)
+		stack    []string
+		cycles   [][]string
+		strongly func(string)
+	)
+	strongly = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range g.Dependencies(v) {
+			if _, visited := index[w]; !visited {
+				strongly(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && index[w] < lowlink[v] {
+				lowlink[v] = index[w]
+			}
+		}
+		if lowlink[v] != index[v] {
+			return
+		}
+		component := make(map[string]bool)
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component[w] = true
+			if w == v {
+				break
+			}
+		}
+		if len(component) > 1 {
+			cycles = append(cycles, g.cycleIn(component))
+		}
+	}
+	for _, v := range g.Nodes() {
+		if _, visited := index[v]; !visited {
+			strongly(v)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// cycleIn returns a cycle within strongly connected component, starting with its smallest address
+func (g *Graph) cycleIn(component map[string]bool) []string {
+	start := sortedKeys(component)[0]
+	// breadth first search for the shortest path back to start
+	prev := map[string]string{start: ""}
+	queue := []string{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, dep := range g.Dependencies(cur) {
+			if !component[dep] {
+				continue
+			}
+			if dep == start {
+				cycle := []string{}
+				for v := cur; v != ""; v = prev[v] {
+					cycle = append([]string{v}, cycle...)
+				}
+				return cycle
+			}
+			if _, seen := prev[dep]; !seen {
+				prev[dep] = cur
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return []string{start}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
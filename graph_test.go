@@ -0,0 +1,96 @@
+package tfparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func parseGraphTestdata(t *testing.T) *Graph {
+	config, err := ParseFile("testdata/graph/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	return NewGraph(config)
+}
+
+func TestGraphNodes(t *testing.T) {
+	g := parseGraphTestdata(t)
+	expected := []string{"aws_instance.bastion", "data.aws_ami.ubuntu", "local.name", "local.tags",
+		"module.peering", "module.vpc", "output.vpc_id", "var.env", "var.region"}
+	if nodes := g.Nodes(); !reflect.DeepEqual(nodes, expected) {
+		t.Fatalf("Unexpected graph nodes %v, expected %v", nodes, expected)
+	}
+}
+
+func TestGraphDependencies(t *testing.T) {
+	g := parseGraphTestdata(t)
+	expected := map[string][]string{
+		"local.name":           {"var.env"},
+		"local.tags":           {"var.env"},
+		"aws_instance.bastion": {"data.aws_ami.ubuntu", "local.tags", "module.vpc"},
+		"module.vpc":           {"local.name", "var.region"},
+		"module.peering":       {"aws_instance.bastion", "module.vpc", "var.region"},
+		"output.vpc_id":        {"module.vpc"},
+	}
+	for address, deps := range expected {
+		if d := g.Dependencies(address); !reflect.DeepEqual(d, deps) {
+			t.Fatalf("Unexpected dependencies of %#q: %v, expected %v", address, d, deps)
+		}
+	}
+}
+
+func TestGraphAllDependents(t *testing.T) {
+	g := parseGraphTestdata(t)
+	expected := []string{"aws_instance.bastion", "module.peering", "output.vpc_id"}
+	if d := g.AllDependents("module.vpc"); !reflect.DeepEqual(d, expected) {
+		t.Fatalf("Unexpected dependents of 'module.vpc': %v, expected %v", d, expected)
+	}
+}
+
+func TestGraphTopologicalSort(t *testing.T) {
+	g := parseGraphTestdata(t)
+	sorted, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("TopologicalSort returned an error, %v", err)
+	}
+	expected := []string{"data.aws_ami.ubuntu", "var.env", "local.name", "local.tags", "var.region",
+		"module.vpc", "aws_instance.bastion", "module.peering", "output.vpc_id"}
+	if !reflect.DeepEqual(sorted, expected) {
+		t.Fatalf("Unexpected topological order %v, expected %v", sorted, expected)
+	}
+}
+
+func TestGraphCycles(t *testing.T) {
+	config, err := ParseString(`
+module "a" {
+  source = "./a"
+  input  = module.c.output
+}
+module "b" {
+  source = "./b"
+  input  = module.a.output
+}
+module "c" {
+  source = "./c"
+  input  = module.b.output
+}
+module "d" {
+  source = "./d"
+  input  = module.a.output
+}`)
+	if err != nil {
+		t.Fatalf("ParseString returned an error, %v", err)
+	}
+	g := NewGraph(config)
+	expected := [][]string{{"module.a", "module.c", "module.b"}}
+	if cycles := g.Cycles(); !reflect.DeepEqual(cycles, expected) {
+		t.Fatalf("Unexpected cycles %v, expected %v", cycles, expected)
+	}
+	_, err = g.TopologicalSort()
+	if err == nil {
+		t.Fatal("TopologicalSort did not return an error for graph with cycle")
+	}
+	if err.Error() != "Dependency cycle found: module.a -> module.c -> module.b" {
+		t.Fatalf("Unexpected error returned by TopologicalSort: %v", err)
+	}
+}
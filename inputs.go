@@ -0,0 +1,204 @@
+package tfparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ModuleInput is the value of an input variable passed into a module call
+type ModuleInput struct {
+	Module  string `json:"module"`            // name of module call with instance key, see ModuleInstance.Name
+	Name    string `json:"name"`              // name of input variable
+	Value   Value  `json:"value"`             // Unknown if the value depends on resources or outputs of modules
+	Default bool   `json:"default,omitempty"` // value is the default of variable, it is not set in module call
+	Pos     Pos    `json:"pos"`               // position of the parameter, or of the variable for defaults
+}
+
+// String returns the input as it is reported to reviewers, e.g. 'vpc.name will be "main"'
+func (in *ModuleInput) String() string {
+	s := fmt.Sprintf("%v.%v will be %v", in.Module, in.Name, FormatValue(in.Value))
+	if in.Default {
+		s += " (default)"
+	}
+	return s
+}
+
+// moduleMetaArguments are parameters of module call which are not input variables
+var moduleMetaArguments = map[string]bool{
+	"source": true, "version": true, "count": true, "for_each": true, "depends_on": true,
+}
+
+// EffectiveInputs returns values of input variables passed into instances of module calls of config,
+// see ExpandModules, sorted by instance and variable name. Parameters of calls are evaluated with
+// variables of config, which are set from vars (see ParseVars), or to their defaults, and with its
+// local values. Variables without value are Unknown. modules are called modules keyed by call name,
+// see ParseModules, their variables which are not set in a call are reported with the default
+// value; modules may be nil
+func EffectiveInputs(config *TFconfig, modules map[string]*TFconfig, vars map[string]Value) ([]*ModuleInput, error) {
+	ctx, err := NewEvalContext(config, vars)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := expandModules(config, ctx)
+	if err != nil {
+		return nil, err
+	}
+	var inputs []*ModuleInput
+	for _, in := range instances {
+		instanceInputs, err := moduleInputs(in, config.Modules[in.Module], modules[in.Module])
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, instanceInputs...)
+	}
+	return inputs, nil
+}
+
+// moduleInputs returns parameters of instance in of module call m along with defaults of variables
+// of called module child, which may be nil
+func moduleInputs(in *ModuleInstance, m *Module, child *TFconfig) ([]*ModuleInput, error) {
+	var inputs []*ModuleInput
+	for _, param := range sortedValueKeys(in.Parameters) {
+		inputs = append(inputs, &ModuleInput{Module: in.Name(), Name: param, Value: in.Parameters[param], Pos: m.ParametersPos[param]})
+	}
+	if child == nil {
+		return inputs, nil
+	}
+	for _, b := range child.variables() {
+		expr, exists := b.exprs["default"]
+		if _, set := m.Parameters[b.Labels[0]]; set || !exists {
+			continue
+		}
+		v, err := Eval(expr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%v: Unable to evaluate default of variable %#q: %v", b.AttributesPos["default"], b.Labels[0], err)
+		}
+		inputs = append(inputs, &ModuleInput{in.Name(), b.Labels[0], v, true, b.Pos})
+	}
+	sort.SliceStable(inputs, func(i, j int) bool { return inputs[i].Name < inputs[j].Name })
+	return inputs, nil
+}
+
+// expr returns parameter name as it is written. Modules which are not parsed from configuration
+// have string parameters only
+func (m *Module) expr(name string) string {
+	if expr, exists := m.exprs[name]; exists {
+		return expr
+	}
+	return strconv.Quote(m.Parameters[name])
+}
+
+// variables returns 'variable' blocks of c in order of declaration
+func (c *TFconfig) variables() []*Block {
+	var blocks []*Block
+	for _, b := range c.Blocks {
+		if b.Type == "variable" && len(b.Labels) == 1 {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// NewEvalContext returns context to evaluate expressions of config with: variables are set from vars,
+// or to their defaults, or to Unknown if neither is set, local values of config are evaluated
+func NewEvalContext(config *TFconfig, vars map[string]Value) (*EvalContext, error) {
+	ctx := &EvalContext{Variables: make(map[string]Value), Locals: make(map[string]Value)}
+	for _, b := range config.variables() {
+		name := b.Labels[0]
+		if v, exists := vars[name]; exists {
+			ctx.Variables[name] = v
+			continue
+		}
+		expr, exists := b.exprs["default"]
+		if !exists {
+			ctx.Variables[name] = Unknown
+			continue
+		}
+		v, err := Eval(expr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%v: Unable to evaluate default of variable %#q: %v", b.AttributesPos["default"], name, err)
+		}
+		ctx.Variables[name] = v
+	}
+
+	// local values are evaluated after the ones they refer to
+	visiting := make(map[string]bool)
+	var evalLocal func(name string) error
+	evalLocal = func(name string) error {
+		if _, done := ctx.Locals[name]; done {
+			return nil
+		}
+		pos := config.Objects["local."+name].Pos
+		if visiting[name] {
+			return fmt.Errorf("%v: Local value %#q refers to itself", pos, name)
+		}
+		visiting[name] = true
+		expr := config.locals[name]
+		for _, ref := range findReferences(expr) {
+			if !strings.HasPrefix(ref, "local.") {
+				continue
+			}
+			dep := strings.SplitN(strings.TrimPrefix(ref, "local."), ".", 2)[0]
+			if _, exists := config.locals[dep]; exists {
+				if err := evalLocal(dep); err != nil {
+					return err
+				}
+			}
+		}
+		v, err := Eval(expr, ctx)
+		if err != nil {
+			return fmt.Errorf("%v: Unable to evaluate local value %#q: %v", pos, name, err)
+		}
+		ctx.Locals[name] = v
+		return nil
+	}
+	for _, name := range sortedStringKeys(config.locals) {
+		if err := evalLocal(name); err != nil {
+			return nil, err
+		}
+	}
+	return ctx, nil
+}
+
+// ParseVars parses variable definitions, e.g. terraform.tfvars, written in terraform syntax or
+// in JSON if filename has '.json' suffix. Values must not refer to anything
+func ParseVars(data []byte, filename string) (map[string]Value, error) {
+	vars := make(map[string]Value)
+	if strings.HasSuffix(filename, ".json") {
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return nil, fmt.Errorf("%v: %v", filename, err)
+		}
+		for name, v := range decoded {
+			vars[name] = fromJSON(v)
+		}
+		return vars, nil
+	}
+	p := newParser(string(data), filename, &TFconfig{})
+	for p.popWhitespaces(); p.i < len(p.data); p.popWhitespaces() {
+		pos := p.pos()
+		name := p.pop()
+		if err := p.popToken("="); err != nil {
+			return nil, fmt.Errorf("%v: %v", pos, err)
+		}
+		v, err := Eval(p.popExpression(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("%v: Invalid value of variable %#q: %v", pos, name, err)
+		}
+		vars[name] = v
+	}
+	return vars, p.err
+}
+
+// ParseVarsFile reads filename and parses it with ParseVars
+func ParseVarsFile(filename string) (map[string]Value, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseVars(data, filename)
+}
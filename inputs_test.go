@@ -0,0 +1,122 @@
+package tfparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEffectiveInputs(t *testing.T) {
+	config, err := ParseDir("testdata/inputs/root")
+	if err != nil {
+		t.Fatalf("ParseDir returned an error, %v", err)
+	}
+	child, err := ParseDir("testdata/inputs/modules/vpc")
+	if err != nil {
+		t.Fatalf("ParseDir returned an error, %v", err)
+	}
+	modules := map[string]*TFconfig{"vpc": child, "peer": child, "subnet": child}
+	vars, err := ParseVarsFile("testdata/inputs/root/terraform.tfvars")
+	if err != nil {
+		t.Fatalf("ParseVarsFile returned an error, %v", err)
+	}
+	inputs, err := EffectiveInputs(config, modules, vars)
+	if err != nil {
+		t.Fatalf("EffectiveInputs returned an error, %v", err)
+	}
+	// instances of 'peer' and 'subnet' are covered by TestExpandModules
+	expected := []string{
+		`peer["a"].cidr will be "10.1.0.0/16"`,
+		`peer["a"].name will be "peer-a"`,
+		`peer["a"].nat_ip will be null (default)`,
+		`peer["a"].tags will be { managed = "tfparser" } (default)`,
+		`peer["a"].zones will be [] (default)`,
+		`vpc.cidr will be "10.0.16.0/20"`,
+		`vpc.name will be "Development VPC"`,
+		`vpc.nat_ip will be (known after apply)`,
+		`vpc.tags will be { managed = "tfparser" } (default)`,
+		`vpc.zones will be ["us-east-1a", "us-east-1b"]`,
+	}
+	var got []string
+	byName := make(map[string]*ModuleInput)
+	for _, in := range inputs {
+		if in.Module == "vpc" || in.Module == `peer["a"]` {
+			got = append(got, in.String())
+			byName[in.Module+"."+in.Name] = in
+		}
+	}
+	if len(inputs) != 25 {
+		t.Errorf("Unexpected number of inputs %v", len(inputs))
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Unexpected inputs:\n%v\nexpected:\n%v", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+	if pos := byName["vpc.name"].Pos; pos.Line != 22 {
+		t.Errorf("Unexpected position of vpc.name %v", pos)
+	}
+	if in := byName["vpc.tags"]; in.Pos.Line != 13 || !in.Default {
+		t.Errorf("Unexpected position of vpc.tags default %v", in.Pos)
+	}
+}
+
+func TestEffectiveInputsWithoutVars(t *testing.T) {
+	config, err := ParseDir("testdata/inputs/root")
+	if err != nil {
+		t.Fatalf("ParseDir returned an error, %v", err)
+	}
+	inputs, err := EffectiveInputs(config, nil, nil)
+	if err != nil {
+		t.Fatalf("EffectiveInputs returned an error, %v", err)
+	}
+	// 'env' has no default, so does the local value depending on it
+	for _, in := range inputs {
+		if in.Module == "vpc" && in.Name == "name" && !isUnknown(in.Value) {
+			t.Errorf("Unexpected value of vpc.name %v", FormatValue(in.Value))
+		}
+	}
+}
+
+func TestEffectiveInputsErrors(t *testing.T) {
+	tests := []struct {
+		code, want string
+	}{
+		{`module "a" {
+  source = "./a"
+  name   = var.missing
+}`, "undeclared input variable"},
+		{`locals {
+  a = local.b
+  b = local.a
+}`, "refers to itself"},
+		{`variable "x" { default = var.y }`, "Unable to evaluate default"},
+	}
+	for _, tt := range tests {
+		config, err := ParseString(tt.code)
+		if err != nil {
+			t.Fatalf("ParseString returned an error, %v", err)
+		}
+		_, err = EffectiveInputs(config, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("EffectiveInputs returned error %v, expected %#q", err, tt.want)
+		}
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]byte("env = \"prod\"\ncount = 3\nzones = [\"a\", \"b\"]\n"), "prod.tfvars")
+	if err != nil {
+		t.Fatalf("ParseVars returned an error, %v", err)
+	}
+	if vars["env"] != "prod" || vars["count"] != 3.0 || FormatValue(vars["zones"]) != `["a", "b"]` {
+		t.Errorf("Unexpected variables %v", vars)
+	}
+	vars, err = ParseVars([]byte(`{"env": "prod", "tags": {"a": 1}}`), "prod.tfvars.json")
+	if err != nil {
+		t.Fatalf("ParseVars returned an error, %v", err)
+	}
+	if vars["env"] != "prod" || FormatValue(vars["tags"]) != `{ a = 1 }` {
+		t.Errorf("Unexpected variables %v", vars)
+	}
+	if _, err := ParseVars([]byte("env = var.x\n"), "bad.tfvars"); err == nil || !strings.Contains(err.Error(), "bad.tfvars:1:1") {
+		t.Errorf("ParseVars returned error %v, expected position of the variable", err)
+	}
+}
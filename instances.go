@@ -0,0 +1,173 @@
+package tfparser
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ModuleInstance is an instance of module call. Calls with 'count' or 'for_each' have an instance
+// per index or key, other calls have a single instance
+type ModuleInstance struct {
+	Module     string           `json:"module"`        // name of module call
+	Key        Value            `json:"key,omitempty"` // index for 'count', key for 'for_each', nil for calls without them
+	Parameters map[string]Value `json:"parameters"`    // parameters evaluated for the instance, without meta-arguments
+	Pos        Pos              `json:"pos"`           // position of the 'module' keyword
+
+	ctx *EvalContext // context parameters are evaluated with, with 'each' or 'count' of the instance
+}
+
+// Name returns name of module call with instance key, e.g. 'vpc', 'vpc[0]' or 'vpc["eu"]'.
+// Instance key is '[*]' if count or for_each of the call is Unknown
+func (in *ModuleInstance) Name() string {
+	switch key := in.Key.(type) {
+	case nil:
+		return in.Module
+	case float64:
+		return fmt.Sprintf("%v[%v]", in.Module, formatNumber(key))
+	case string:
+		return fmt.Sprintf("%v[%v]", in.Module, strconv.Quote(key))
+	}
+	return in.Module + "[*]"
+}
+
+// Address returns address of the instance, e.g. 'module.vpc["eu"]'
+func (in *ModuleInstance) Address() string {
+	return "module." + in.Name()
+}
+
+// ExpandModules returns instances of module calls of config, sorted by call name and then by index
+// or key. 'count' and 'for_each' are evaluated with variables set from vars as in EffectiveInputs,
+// 'each.key', 'each.value' and 'count.index' are substituted in parameters of instances. If count
+// or for_each depends on resources, the call has a single instance with Unknown key and 'each' or
+// 'count' attributes are Unknown in its parameters
+func ExpandModules(config *TFconfig, vars map[string]Value) ([]*ModuleInstance, error) {
+	ctx, err := NewEvalContext(config, vars)
+	if err != nil {
+		return nil, err
+	}
+	return expandModules(config, ctx)
+}
+
+func expandModules(config *TFconfig, ctx *EvalContext) ([]*ModuleInstance, error) {
+	var instances []*ModuleInstance
+	for _, name := range sortedModuleNames(config) {
+		m := config.Modules[name]
+		callInstances, err := expandModule(name, m, ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, in := range callInstances {
+			in.Parameters = make(map[string]Value)
+			for _, param := range sortedStringKeys(m.Parameters) {
+				if moduleMetaArguments[param] {
+					continue
+				}
+				v, err := Eval(m.expr(param), in.ctx)
+				if err != nil {
+					return nil, fmt.Errorf("%v: Unable to evaluate parameter %#q of module %#q: %v", m.ParametersPos[param], param, in.Name(), err)
+				}
+				in.Parameters[param] = v
+			}
+		}
+		instances = append(instances, callInstances...)
+	}
+	return instances, nil
+}
+
+// expandModule returns instances of module call m without parameters
+func expandModule(name string, m *Module, ctx *EvalContext) ([]*ModuleInstance, error) {
+	_, hasCount := m.Parameters["count"]
+	_, hasForEach := m.Parameters["for_each"]
+	instance := func(key Value, each, count map[string]Value) *ModuleInstance {
+		instanceCtx := *ctx
+		instanceCtx.Each, instanceCtx.Count = each, count
+		return &ModuleInstance{Module: name, Key: key, Pos: m.Pos, ctx: &instanceCtx}
+	}
+	switch {
+	case hasCount && hasForEach:
+		return nil, fmt.Errorf("%v: Module %#q has both %#q and %#q", m.Pos, name, "count", "for_each")
+	case hasCount:
+		pos := m.ParametersPos["count"]
+		v, err := Eval(m.expr("count"), ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%v: Unable to evaluate count of module %#q: %v", pos, name, err)
+		}
+		if isUnknown(v) {
+			return []*ModuleInstance{instance(Unknown, nil, map[string]Value{"index": Unknown})}, nil
+		}
+		n, err := toInt(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%v: Invalid count of module %#q, non-negative whole number required, found %v", pos, name, FormatValue(v))
+		}
+		instances := []*ModuleInstance{}
+		for i := 0; i < n; i++ {
+			instances = append(instances, instance(float64(i), nil, map[string]Value{"index": float64(i)}))
+		}
+		return instances, nil
+	case hasForEach:
+		pos := m.ParametersPos["for_each"]
+		v, err := Eval(m.expr("for_each"), ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%v: Unable to evaluate for_each of module %#q: %v", pos, name, err)
+		}
+		unknown := instance(Unknown, map[string]Value{"key": Unknown, "value": Unknown}, nil)
+		instances := []*ModuleInstance{}
+		switch v := v.(type) {
+		case unknownValue:
+			return []*ModuleInstance{unknown}, nil
+		case map[string]Value:
+			for _, key := range sortedValueKeys(v) {
+				instances = append(instances, instance(key, map[string]Value{"key": key, "value": v[key]}, nil))
+			}
+			return instances, nil
+		case []Value:
+			if isListExpression(m.expr("for_each")) {
+				return nil, fmt.Errorf("%v: Invalid for_each of module %#q, for_each argument must be a map, or set of strings, found list, convert it with toset", pos, name)
+			}
+			// set of strings, each.value is the same as each.key
+			set, err := funcToSet([]Value{v})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range set.([]Value) {
+				if isUnknown(item) {
+					return []*ModuleInstance{unknown}, nil
+				}
+				key, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%v: Invalid for_each of module %#q, set of strings required, found %v element", pos, name, typeName(item))
+				}
+				instances = append(instances, instance(key, map[string]Value{"key": key, "value": key}, nil))
+			}
+			return instances, nil
+		}
+		return nil, fmt.Errorf("%v: Invalid for_each of module %#q, map or set of strings required, found %v", pos, name, typeName(v))
+	}
+	return []*ModuleInstance{instance(nil, nil, nil)}, nil
+}
+
+// listFunctions are functions returning lists, see isListExpression
+var listFunctions = map[string]bool{
+	"chunklist": true, "coalescelist": true, "compact": true, "concat": true, "distinct": true,
+	"flatten": true, "keys": true, "range": true, "regexall": true, "reverse": true, "slice": true,
+	"sort": true, "split": true, "tolist": true, "values": true,
+}
+
+// isListExpression tells if expr is a tuple, a list 'for' expression or a call of function
+// returning list. Lists and sets are both []Value when evaluated, so they are told apart by
+// expression, types of references are not known and they are not reported as lists
+func isListExpression(expr string) bool {
+	node, err := parseExpression(expr)
+	if err != nil {
+		return false
+	}
+	switch n := node.(type) {
+	case *tupleNode:
+		return true
+	case *forNode:
+		return n.key == nil
+	case *callNode:
+		return listFunctions[n.name]
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package tfparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandModules(t *testing.T) {
+	config, err := ParseDir("testdata/inputs/root")
+	if err != nil {
+		t.Fatalf("ParseDir returned an error, %v", err)
+	}
+	instances, err := ExpandModules(config, map[string]Value{"env": "dev", "zones": []Value{"eu", "us", "eu"}})
+	if err != nil {
+		t.Fatalf("ExpandModules returned an error, %v", err)
+	}
+	expected := []struct {
+		address, name, cidr string
+	}{
+		{`module.peer["eu"]`, `"peer-eu"`, `"10.1.0.0/16"`},
+		{`module.peer["us"]`, `"peer-us"`, `"10.1.0.0/16"`},
+		{`module.subnet[0]`, `"subnet-0"`, `"10.0.0.0/24"`},
+		{`module.subnet[1]`, `"subnet-1"`, `"10.0.1.0/24"`},
+		{`module.subnet[2]`, `"subnet-2"`, `"10.0.2.0/24"`},
+		{`module.vpc`, `"Dev VPC"`, `"10.0.16.0/20"`},
+	}
+	if len(instances) != len(expected) {
+		t.Fatalf("Unexpected number of instances %v, expected %v", len(instances), len(expected))
+	}
+	for i, e := range expected {
+		in := instances[i]
+		if in.Address() != e.address || FormatValue(in.Parameters["name"]) != e.name || FormatValue(in.Parameters["cidr"]) != e.cidr {
+			t.Errorf("Unexpected instance %v with name %v and cidr %v, expected %+v", in.Address(),
+				FormatValue(in.Parameters["name"]), FormatValue(in.Parameters["cidr"]), e)
+		}
+	}
+	if _, exists := instances[0].Parameters["for_each"]; exists {
+		t.Errorf("Meta-arguments are not expected in parameters")
+	}
+}
+
+func TestExpandModulesForEachMap(t *testing.T) {
+	config, err := ParseString(`
+module "peering" {
+  source   = "./peering"
+  for_each = { eu = "10.1.0.0/16", us = "10.2.0.0/16" }
+
+  peer_cidr = each.value
+}
+
+module "none" {
+  source = "./none"
+  count  = 0
+}
+
+module "dynamic" {
+  source   = "./dynamic"
+  for_each = aws_vpc.all
+
+  vpc_id = each.value.id
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseString returned an error, %v", err)
+	}
+	instances, err := ExpandModules(config, nil)
+	if err != nil {
+		t.Fatalf("ExpandModules returned an error, %v", err)
+	}
+	var got []string
+	for _, in := range instances {
+		got = append(got, in.Address()+" "+FormatValue(in.Parameters))
+	}
+	expected := []string{
+		`module.dynamic[*] { vpc_id = (known after apply) }`,
+		`module.peering["eu"] { peer_cidr = "10.1.0.0/16" }`,
+		`module.peering["us"] { peer_cidr = "10.2.0.0/16" }`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Unexpected instances:\n%v\nexpected:\n%v", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestExpandModulesErrors(t *testing.T) {
+	tests := []struct {
+		code, want string
+	}{
+		{`module "a" {
+  source = "./a"
+  count  = -1
+}`, "Invalid count"},
+		{`module "a" {
+  source   = "./a"
+  for_each = "eu"
+}`, "map or set of strings required"},
+		{`module "a" {
+  source   = "./a"
+  for_each = [1, 2]
+}`, "for_each argument must be a map, or set of strings"},
+		{`module "a" {
+  source   = "./a"
+  for_each = ["eu", "us"]
+}`, "for_each argument must be a map, or set of strings"},
+		{`module "a" {
+  source   = "./a"
+  for_each = [for zone in ["eu", "us"] : zone]
+}`, "for_each argument must be a map, or set of strings"},
+		{`module "a" {
+  source   = "./a"
+  for_each = split(",", "eu,us")
+}`, "for_each argument must be a map, or set of strings"},
+		{`module "a" {
+  source   = "./a"
+  for_each = toset([true])
+}`, "set of strings required"},
+		{`module "a" {
+  source   = "./a"
+  count    = 1
+  for_each = {}
+}`, "has both"},
+		{`module "a" {
+  source = "./a"
+  name   = each.key
+}`, "outside of a block with for_each"},
+	}
+	for _, tt := range tests {
+		config, err := ParseString(tt.code)
+		if err != nil {
+			t.Fatalf("ParseString returned an error, %v", err)
+		}
+		_, err = ExpandModules(config, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ExpandModules returned error %v, expected %#q", err, tt.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package tfparser
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -13,55 +14,93 @@ func (p *parser) skipComment() error {
 	if p.i >= len(p.data) {
 		return nil
 	}
-	if p.data[p.i] == '#' {
-		p.skipTillEOL()
-		return nil
+	if p.data[p.i] == '/' && p.i+1 >= len(p.data) {
+		p.err = fmt.Errorf("Unexpected end of file after '/'")
+		return p.err
 	}
-	if p.data[p.i] == '/' {
-		if p.i+1 > len(p.data) {
-			p.err = fmt.Errorf("Unexpected end of file after '/'")
-			return p.err
-		}
-		// Single line comment starting with // ?
-		if p.data[p.i+1] == '/' {
-			p.skipTillEOL()
-			return nil
-		}
+	switch {
+	case p.data[p.i] == '#':
+		p.readIgnoreDirective(p.i + 1)
+		p.skipTillEOL()
+	case strings.HasPrefix(p.data[p.i:], "//"):
+		p.readIgnoreDirective(p.i + 2)
+		p.skipTillEOL()
+	case strings.HasPrefix(p.data[p.i:], "/*"):
 		return p.skipMulitlineComment()
 	}
 	return nil
 }
 
+// skipTillEOL skips single line comment along with the newline after it, see scanComment
 func (p *parser) skipTillEOL() {
-	for ; p.i < len(p.data) && p.data[p.i] != '\n'; p.i++ {
-	}
-	p.i++
+	p.i, _ = scanComment(p.data, p.i)
 	p.skipNewLine()
 }
 
+// skipMulitlineComment skips multiline comment '/* */' along with the newline after it,
+// see scanComment
 func (p *parser) skipMulitlineComment() error {
-	if p.i > len(p.data) {
-		return nil
+	end, err := scanComment(p.data, p.i)
+	if err != nil {
+		p.err = err
+		return p.err
 	}
-	if p.i+1 >= len(p.data) || (p.data[p.i] != '/' && p.data[p.i+1] != '*') {
-		return nil
+	p.i = end
+	p.skipNewLine()
+	return nil
+}
+
+// scanComment returns index right after the comment started at data[i]. Single line comments end
+// before the newline, multiline comments '/* */' may be nested
+func scanComment(data string, i int) (int, error) {
+	if !strings.HasPrefix(data[i:], "/*") {
+		eol := strings.IndexAny(data[i:], newlines)
+		if eol < 0 {
+			return len(data), nil
+		}
+		return i + eol, nil
 	}
 	nestedCount := 0
-	for ; p.i+1 < len(p.data); p.i++ {
-		if p.data[p.i] == '/' && p.data[p.i+1] == '*' {
+	for ; i+1 < len(data); i++ {
+		if data[i] == '/' && data[i+1] == '*' {
 			nestedCount++
-		}
-		if p.data[p.i] == '*' && p.data[p.i+1] == '/' {
-			p.i += 2
+			i++
+		} else if data[i] == '*' && data[i+1] == '/' {
 			nestedCount--
+			i++
 			if nestedCount == 0 {
-				p.skipNewLine()
-				return nil
+				return i + 1, nil
 			}
 		}
 	}
-	p.err = fmt.Errorf("Unable to find closing multiline comment")
-	return p.err
+	return 0, fmt.Errorf("Unable to find closing multiline comment")
+}
+
+const ignoreDirective = "tfparser:ignore"
+
+// readIgnoreDirective reads comment starting at data[start], and if it is
+// '# tfparser:ignore rule-id, ...' stores rules to be ignored on this and the next line
+func (p *parser) readIgnoreDirective(start int) {
+	end := strings.IndexByte(p.data[start:], '\n')
+	if end < 0 {
+		end = len(p.data) - start
+	}
+	comment := strings.TrimSpace(p.data[start : start+end])
+	if !strings.HasPrefix(comment, ignoreDirective) {
+		return
+	}
+	rest := comment[len(ignoreDirective):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return
+	}
+	rules := strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r'
+	})
+	if p.config.ignores == nil {
+		p.config.ignores = make(map[ignoreKey][]string)
+	}
+	pos := p.pos()
+	p.config.ignores[ignoreKey{pos.Filename, pos.Line}] = rules
 }
 
 var newlines = "\n\r"
@@ -80,25 +119,186 @@ func (p *parser) skipBlock() error {
 		return nil
 	}
 	bracesBalance := 0
-	for ; p.i < len(p.data); p.i++ {
-		if p.data[p.i] == '{' {
+	for p.i < len(p.data) {
+		switch p.data[p.i] {
+		case '{':
 			bracesBalance++
-		}
-		if p.data[p.i] == '}' {
+		case '}':
 			bracesBalance--
+		case '"':
+			// braces inside of strings are not counted
+			p.i = scanString(p.data, p.i)
+			continue
+		case '#', '/':
+			i := p.i
+			p.skipComment()
+			if i != p.i {
+				continue
+			}
 		}
+		p.i++
 		if bracesBalance == 0 {
-			p.i++
 			p.skipNewLine()
 			return nil
 		}
-		p.skipComment()
 	}
-	if bracesBalance != 0 {
-		p.err = fmt.Errorf("Unable to find closing brace for block")
-		return p.err
+	p.err = fmt.Errorf("Unable to find closing brace for block")
+	return p.err
+}
+
+// popExpression pops value of an attribute as raw text: everything till the end of line, unless
+// line ends inside of brackets, parentheses, string or heredoc. Trailing comments are not included
+func (p *parser) popExpression() string {
+	start := p.i
+	depth := 0
+	end := -1
+	for p.i < len(p.data) && end < 0 {
+		switch c := p.data[p.i]; {
+		case c == '"':
+			p.i = scanString(p.data, p.i)
+		case c == '<' && strings.HasPrefix(p.data[p.i:], "<<"):
+			p.i = scanHeredoc(p.data, p.i)
+		case c == '#' || strings.HasPrefix(p.data[p.i:], "//") || strings.HasPrefix(p.data[p.i:], "/*"):
+			if depth == 0 {
+				end = p.i
+			} else {
+				p.skipComment()
+			}
+		case c == '{' || c == '[' || c == '(':
+			depth++
+			p.i++
+		case c == '}' || c == ']' || c == ')':
+			if depth == 0 {
+				end = p.i
+				break
+			}
+			depth--
+			p.i++
+		case (c == '\n' || c == ',') && depth == 0:
+			end = p.i
+		default:
+			p.i++
+		}
 	}
-	return nil
+	if end < 0 {
+		end = len(p.data)
+	}
+	if p.i > len(p.data) {
+		p.i = len(p.data)
+	}
+	expr := strings.TrimSpace(p.data[start:end])
+	p.popWhitespaces()
+	return expr
+}
+
+// scanString returns index right after the closing quote of the string started at data[i].
+// Template sequences '${...}' and '%{...}' may contain nested strings
+func scanString(data string, i int) int {
+	end, _ := scanStringEnd(data, i)
+	return end
+}
+
+// scanStringEnd is scanString, which also tells if the string is closed. Unclosed string runs
+// till the end of data
+func scanStringEnd(data string, i int) (int, bool) {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, true
+		case '$', '%':
+			if i+1 < len(data) && data[i+1] == '{' {
+				i = scanTemplate(data, i+1) - 1
+			}
+		}
+	}
+	return len(data), false
+}
+
+// scanTemplate returns index right after the closing brace of template sequence started at data[i]
+func scanTemplate(data string, i int) int {
+	depth := 0
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '"':
+			i = scanString(data, i) - 1
+		}
+	}
+	return len(data)
+}
+
+// scanHeredoc returns index right after the end of heredoc started at data[i] ('<<EOT' or '<<-EOT').
+// If data[i] does not start a heredoc, index after '<<' is returned
+func scanHeredoc(data string, i int) int {
+	j := i + 2
+	if j < len(data) && data[j] == '-' {
+		j++
+	}
+	eol := strings.IndexByte(data[j:], '\n')
+	if eol < 0 {
+		return i + 2
+	}
+	marker := strings.TrimSpace(data[j : j+eol])
+	if marker == "" || strings.ContainsAny(marker, symbols+whitespaces) {
+		return i + 2
+	}
+	for k := j + eol + 1; k < len(data); {
+		eol := strings.IndexByte(data[k:], '\n')
+		if eol < 0 {
+			eol = len(data) - k
+		}
+		if strings.TrimSpace(data[k:k+eol]) == marker {
+			return k + eol
+		}
+		k += eol + 1
+	}
+	return len(data)
+}
+
+// unquote returns content of expr if expr is a single quoted string, otherwise expr as is
+func unquote(expr string) string {
+	if len(expr) >= 2 && expr[0] == '"' && scanString(expr, 0) == len(expr) {
+		return expr[1 : len(expr)-1]
+	}
+	return expr
+}
+
+// unquoteString returns value of expr if expr is a single quoted string, with escape sequences
+// interpreted, see unescape, otherwise expr as is
+func unquoteString(expr string) string {
+	s := unquote(expr)
+	if len(s) == len(expr) {
+		return s
+	}
+	return unescape(s)
+}
+
+// unescape interprets escape sequences like '\"' or '\n' in content of quoted string s as
+// strconv.Unquote does. Strings with template sequences containing quotes are returned as is
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	if v, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return v
+	}
+	return s
+}
+
+func isIdentifierStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isIdentifierChar(c byte) bool {
+	return isIdentifierStart(c) || c >= '0' && c <= '9' || c == '-'
 }
 
 func (p *parser) peek() string {
@@ -125,6 +325,57 @@ func (p *parser) popToken(tok string) error {
 	return nil
 }
 
+// popList reads list of simple values, like '[aws.alice, aws.bob]'
+func (p *parser) popList() ([]string, error) {
+	p.err = p.popToken("[")
+	if p.err != nil {
+		return nil, p.err
+	}
+	var list []string
+	for {
+		if p.i >= len(p.data) {
+			p.err = fmt.Errorf("Unable to find closing bracket for list")
+			return nil, p.err
+		}
+		switch tok := p.pop(); tok {
+		case "]":
+			return list, nil
+		case ",":
+		default:
+			list = append(list, tok)
+		}
+	}
+}
+
+// pos returns position of current index in data. Line and column are counted from the previously
+// returned position, so walking through data is linear in its size
+func (p *parser) pos() Pos {
+	i := p.i
+	if i > len(p.data) {
+		i = len(p.data)
+	}
+	if i < p.posIndex {
+		p.posIndex, p.posLine, p.posLineStart = 0, 0, 0
+	}
+	for ; p.posIndex < i; p.posIndex++ {
+		if p.data[p.posIndex] == '\n' {
+			p.posLine++
+			p.posLineStart = p.posIndex + 1
+		}
+	}
+	return Pos{
+		Filename: p.filename,
+		Line:     p.posLine + 1,
+		Column:   i - p.posLineStart + 1,
+	}
+}
+
+// peekPos returns position of the next token
+func (p *parser) peekPos() Pos {
+	p.popWhitespaces()
+	return p.pos()
+}
+
 // gets next 'word' from data and returns it along with its length
 func (p *parser) peekWithLength() (string, int) {
 	if p.i >= len(p.data) {
@@ -143,23 +394,23 @@ func (p *parser) peekQuotedStringWithLength() (string, int) {
 	if p.i >= len(p.data) || p.data[p.i] != '"' {
 		return "", 0
 	}
-	for i := p.i + 1; i < len(p.data); i++ {
-		if p.data[i] == '"' && p.data[i-1] != '\\' {
-			return p.data[p.i+1 : i], i - p.i + 1 //len(p.data[p.i+1:i]) + 2
-		}
+	end, closed := scanStringEnd(p.data, p.i)
+	if !closed {
+		return "", 0
 	}
-	return "", 0
+	return p.data[p.i+1 : end-1], end - p.i
 }
 
-var symbols = "{}=\""
+var symbols = "{}=\"[],"
 
 func (p *parser) peekIdentifierWithLength() (string, int) {
-	for i := p.i; i <= len(p.data); i++ {
+	for i := p.i; i < len(p.data); i++ {
 		if strings.Contains(symbols+whitespaces, string(p.data[i])) {
 			return p.data[p.i:i], i - p.i //len(p.data[p.i:i])
 		}
 	}
-	return "", 0
+	// identifier runs till the end of data
+	return p.data[p.i:], len(p.data) - p.i
 }
 
 var whitespaces = "\t\r\n "
@@ -173,3 +424,68 @@ func (p *parser) popWhitespaces() {
 		p.popWhitespaces()
 	}
 }
+
+var operators = []string{"...", "==", "!=", "<=", ">=", "&&", "||", "=>"}
+
+// popTokens splits the rest of data into tokens in trivia preserving mode: whitespace, newlines
+// and comments are tokens too, so joined texts of tokens are data as is. Used for concrete syntax trees
+func (p *parser) popTokens() ([]*Token, error) {
+	var tokens []*Token
+	for p.i < len(p.data) {
+		t, err := p.popTrivia()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+// popTrivia pops a single token in trivia preserving mode, see popTokens. Identifiers are split on
+// symbols and operators, e.g. 'aws.alice' is 3 tokens
+func (p *parser) popTrivia() (*Token, error) {
+	pos := p.pos()
+	kind, end := TokenSymbol, p.i+1
+	switch c := p.data[p.i]; {
+	case c == ' ' || c == '\t':
+		kind = TokenWhitespace
+		for end < len(p.data) && (p.data[end] == ' ' || p.data[end] == '\t') {
+			end++
+		}
+	case c == '\n':
+		kind = TokenNewline
+	case c == '\r' && strings.HasPrefix(p.data[p.i:], "\r\n"):
+		kind, end = TokenNewline, p.i+2
+	case c == '#' || strings.HasPrefix(p.data[p.i:], "//") || strings.HasPrefix(p.data[p.i:], "/*"):
+		var err error
+		kind = TokenComment
+		if end, err = scanComment(p.data, p.i); err != nil {
+			p.err = fmt.Errorf("%v: %v", pos, err)
+			return nil, p.err
+		}
+	case c == '"':
+		var closed bool
+		kind = TokenString
+		if end, closed = scanStringEnd(p.data, p.i); !closed {
+			p.err = fmt.Errorf("%v: Unable to find closing quote", pos)
+			return nil, p.err
+		}
+	case strings.HasPrefix(p.data[p.i:], "<<") && scanHeredoc(p.data, p.i) > p.i+2:
+		kind, end = TokenHeredoc, scanHeredoc(p.data, p.i)
+	case isIdentifierChar(c):
+		kind = TokenIdentifier
+		for end < len(p.data) && isIdentifierChar(p.data[end]) {
+			end++
+		}
+	default:
+		for _, op := range operators {
+			if strings.HasPrefix(p.data[p.i:], op) {
+				end = p.i + len(op)
+				break
+			}
+		}
+	}
+	t := &Token{kind, p.data[p.i:end], pos}
+	p.i = end
+	return t, nil
+}
@@ -10,7 +10,7 @@ func TestSkipTillEOL(t *testing.T) {
 This is first line after comment!
 `
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	p.skipTillEOL()
 	if p.data[p.i] != 'T' {
 		t.Fatalf("After skiping comment next symbol is %#q, expected 'T'", p.data[p.i])
@@ -21,7 +21,7 @@ func TestSkipMulitlineComment(t *testing.T) {
 	testStr := `/*
 	this is multiline comment
 */This is after`
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	p.skipMulitlineComment()
 	if p.data[p.i] != 'T' {
 		t.Fatalf("After skiping multiline comment next symbol is %#q, expected 'T'", p.data[p.i])
@@ -32,7 +32,7 @@ func TestSkipComment(t *testing.T) {
 	testStr := `// Another
 This is after`
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	p.skipComment()
 	if p.data[p.i] != 'T' {
 		t.Fatalf("After skiping comment next symbol is %#q, expected 'T'", p.data[p.i])
@@ -43,7 +43,7 @@ func TestSkipCommentMultilineWithNewline(t *testing.T) {
 	testStr := `/* mulitline */
 T`
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	p.skipComment()
 	if p.data[p.i] != 'T' {
 		t.Fatalf("After skiping comment next symbol is %#q, expected 'T'", p.data[p.i])
@@ -56,7 +56,7 @@ func TestSkipCommentMultilineWithNested(t *testing.T) {
 	*/
 T`
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	p.skipComment()
 	if p.data[p.i] != 'T' {
 		t.Fatalf("After skiping comment next symbol is %#q, expected 'T'", p.data[p.i])
@@ -69,7 +69,7 @@ func TestSkipCommentMultilineUnbalanced(t *testing.T) {
 
 T`
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	err := p.skipComment()
 	if err == nil {
 		t.Fatalf("skipComment did not return error on unbalanced comment")
@@ -84,7 +84,7 @@ func TestSkipBlockSimple(t *testing.T) {
 		simple block
 		}T`
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	p.skipBlock()
 	if p.data[p.i] != 'T' {
 		t.Fatalf("After skiping block next symbol is %#q, expected 'T'", p.data[p.i])
@@ -98,7 +98,7 @@ func TestSkipBlockNested(t *testing.T) {
 		}
 	}T`
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	p.skipBlock()
 	if p.data[p.i] != 'T' {
 		t.Fatalf("After skiping block with nested next symbol is %#q, expected 'T'", p.data[p.i])
@@ -112,7 +112,7 @@ func TestSkipBlockWithCommentedSubblock(t *testing.T) {
 		}*/
 	}T`
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	p.skipBlock()
 	if p.data[p.i] != 'T' {
 		t.Fatalf("After skiping block with commented out block next symbol is %#q, expected 'T'", p.data[p.i])
@@ -126,7 +126,7 @@ func TestSkipBlockUnbalancedReturnsError(t *testing.T) {
 		}*/
 	T`
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	err := p.skipBlock()
 	if err == nil {
 		t.Fatalf("skipBlock did not return error with unbalanced block")
@@ -141,7 +141,7 @@ func TestPeekQuotedStringWithLengthSimple(t *testing.T) {
 	testStr := fmt.Sprintf(`"%v" }
 	`, expected)
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	token, l := p.peekQuotedStringWithLength()
 	if p.err != nil {
 		t.Fatalf("peekQuotedStringWithLength set an error: %q", p.err)
@@ -157,7 +157,7 @@ func TestPeekQuotedStringWithLengthSimple(t *testing.T) {
 func TestPeekIdentifierWithLengthSimple(t *testing.T) {
 	expected := "mytoken"
 	testStr := fmt.Sprintf("%v =", expected)
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	token, l := p.peekIdentifierWithLength()
 	if p.err != nil {
 		t.Fatalf("peekIdentifierWithLength triggered an error: %v", p.err)
@@ -175,7 +175,7 @@ func TestPeekSimpleKeyword(t *testing.T) {
 	testStr := fmt.Sprintf(`
 	%v "mytest`, expected)
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	token := p.peek()
 	if token != expected {
 		t.Fatalf("Unexpected token peeked: %#q, expected %#q", token, expected)
@@ -187,7 +187,7 @@ func TestPeekSimpleQuotedIdentifier(t *testing.T) {
 	testStr := fmt.Sprintf(`
 	"%v" "mytest`, expected)
 
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	token := p.peek()
 	if token != expected {
 		t.Fatalf("Unexpected token peeked: %#q, expected %#q", token, expected)
@@ -198,7 +198,7 @@ func TestPeekSimpleQuotedIdentifierWithEscape(t *testing.T) {
 	expected := "test_\\\"module"
 	testStr := fmt.Sprint(`
 	"test_\"module" "mytest`, expected)
-	p := &parser{testStr, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(testStr, "", &TFconfig{})
 	token := p.peek()
 	if token != expected {
 		t.Fatalf("Unexpected token peeked: %#q, expected %#q", token, expected)
@@ -221,7 +221,7 @@ var moduleTestData1 = `
 `
 
 func TestSomePops(t *testing.T) {
-	p := &parser{moduleTestData1, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(moduleTestData1, "", &TFconfig{})
 	tokens := [...][2]string{
 		{"locals", "{"},
 		{"{", "v"},
@@ -271,7 +271,7 @@ func TestSomePops(t *testing.T) {
 }
 
 func TestSomePeeks(t *testing.T) {
-	p := &parser{moduleTestData1, 0, &TFconfig{}, 0, nil, "", ""}
+	p := newParser(moduleTestData1, "", &TFconfig{})
 	tokens := [...][2]string{
 		{"locals", "l"},
 		{"{", "{"},
@@ -296,3 +296,60 @@ func TestSomePeeks(t *testing.T) {
 
 	}
 }
+
+func TestPopExpression(t *testing.T) {
+	tests := [][2]string{
+		{"var.a # comment\nnext", "var.a"},
+		{`"${join(",", var.list)}" // comment` + "\nnext", `"${join(",", var.list)}"`},
+		{"[\n  \"a\", # first\n  \"b\",\n]\nnext", "[\n  \"a\", # first\n  \"b\",\n]"},
+		{"merge(var.tags, {\n  Name = \"x\"\n})\nnext", "merge(var.tags, {\n  Name = \"x\"\n})"},
+		{"<<EOT\nheredoc ${var.a}\n  EOT\nnext", "<<EOT\nheredoc ${var.a}\n  EOT"},
+		{"1 }", "1"},
+	}
+	for _, test := range tests {
+		p := newParser(test[0], "", &TFconfig{})
+		expr := p.popExpression()
+		if expr != test[1] {
+			t.Fatalf("popExpression returned %#q, expected %#q", expr, test[1])
+		}
+		if next := p.peek(); next != "next" && next != "}" {
+			t.Fatalf("popExpression did not advance to the next token, next one is %#q", next)
+		}
+	}
+}
+
+func TestFindReferences(t *testing.T) {
+	expr := `"${var.env}-${local.name}" /* module.commented */ aws_vpc.main.id[0] "module.quoted" data.aws_ami.x.id`
+	expected := []string{"var.env", "local.name", "aws_vpc.main.id", "data.aws_ami.x.id"}
+	refs := findReferences(expr)
+	if fmt.Sprint(refs) != fmt.Sprint(expected) {
+		t.Fatalf("findReferences returned %v, expected %v", refs, expected)
+	}
+}
+
+func TestFindReferencesDynamicBlocks(t *testing.T) {
+	body := `
+  dynamic "ingress" {
+    for_each = var.rules
+    iterator = rule
+    content {
+      from_port = rule.value.port
+      dynamic "cidr" {
+        for_each = rule.value.cidrs
+        content {
+          block = "${cidr.value}/${local.mask}"
+        }
+      }
+    }
+  }
+  dynamic "egress" {
+    for_each = var.egress
+    content { port = egress.value }
+  }
+  tags = ingress.tags`
+	expected := []string{"var.rules", "local.mask", "var.egress", "ingress.tags"}
+	refs := findReferences(body)
+	if fmt.Sprint(refs) != fmt.Sprint(expected) {
+		t.Fatalf("findReferences returned %v, expected %v", refs, expected)
+	}
+}
@@ -0,0 +1,523 @@
+package tfparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Severity of a lint finding
+type Severity int
+
+// Severities of lint findings, from the least to the most severe
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity returns severity by its name: 'info', 'warning' or 'error'
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if n == name {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown severity %#q", name)
+}
+
+// MarshalJSON encodes severity as its name
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes severity from its name
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	severity, err := ParseSeverity(name)
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// Finding is a problem found in configuration by a lint rule
+type Finding struct {
+	RuleID   string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Pos      Pos      `json:"pos"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%v: %v: %v (%v)", f.Pos, f.Severity, f.Message, f.RuleID)
+}
+
+// Rule inspects configuration and reports findings. Rule only sets Pos and Message of findings,
+// RuleID and Severity are set by Linter
+type Rule interface {
+	ID() string // short name in kebab case, e.g. 'unused-provider'
+	Description() string
+	DefaultSeverity() Severity
+	Check(config *TFconfig) []Finding
+}
+
+// DefaultRules are the rules built into the package, all of them are enabled in a new Linter
+var DefaultRules = []Rule{
+	unpinnedModuleSourceRule{},
+	duplicateModuleParametersRule{},
+	unusedProviderRule{},
+	hardcodedRegionRule{},
+	movedAddressRule{},
+}
+
+// LintConfig enables and disables lint rules and overrides their severities.
+// It is usually read from JSON file, see ReadLintConfig:
+//
+//	{"rules": {"hardcoded-region": {"enabled": false}, "unused-provider": {"severity": "error"}}}
+type LintConfig struct {
+	Rules map[string]RuleConfig `json:"rules"`
+}
+
+// RuleConfig is configuration of a single rule in LintConfig
+type RuleConfig struct {
+	Enabled  *bool     `json:"enabled,omitempty"`  // nil keeps the rule as it is
+	Severity *Severity `json:"severity,omitempty"` // nil keeps the current severity
+}
+
+// ReadLintConfig reads LintConfig from JSON file filename
+func ReadLintConfig(filename string) (*LintConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	config := &LintConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("Unable to read lint config %v: %v", filename, err)
+	}
+	return config, nil
+}
+
+// Linter runs enabled rules against configuration
+type Linter struct {
+	rules    []Rule
+	disabled map[string]bool
+	severity map[string]Severity
+}
+
+// NewLinter returns Linter with all DefaultRules enabled
+func NewLinter() *Linter {
+	l := &Linter{disabled: make(map[string]bool), severity: make(map[string]Severity)}
+	for _, r := range DefaultRules {
+		l.Register(r)
+	}
+	return l
+}
+
+// Register adds rule r to the linter, enabled and with default severity.
+// Rule with the same ID registered earlier is replaced
+func (l *Linter) Register(r Rule) {
+	for i, rule := range l.rules {
+		if rule.ID() == r.ID() {
+			l.rules[i] = r
+			l.severity[r.ID()] = r.DefaultSeverity()
+			return
+		}
+	}
+	l.rules = append(l.rules, r)
+	l.severity[r.ID()] = r.DefaultSeverity()
+}
+
+// Rules returns all registered rules, enabled or not, sorted by ID
+func (l *Linter) Rules() []Rule {
+	rules := append([]Rule(nil), l.rules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID() < rules[j].ID() })
+	return rules
+}
+
+// Enable enables or disables rule with given id
+func (l *Linter) Enable(id string, enabled bool) error {
+	if l.rule(id) == nil {
+		return fmt.Errorf("Unknown lint rule %#q", id)
+	}
+	l.disabled[id] = !enabled
+	return nil
+}
+
+// Enabled tells if rule with given id is enabled
+func (l *Linter) Enabled(id string) bool {
+	return l.rule(id) != nil && !l.disabled[id]
+}
+
+// SetSeverity overrides severity of rule with given id
+func (l *Linter) SetSeverity(id string, severity Severity) error {
+	if l.rule(id) == nil {
+		return fmt.Errorf("Unknown lint rule %#q", id)
+	}
+	l.severity[id] = severity
+	return nil
+}
+
+// Severity returns severity of findings of rule with given id
+func (l *Linter) Severity(id string) Severity {
+	return l.severity[id]
+}
+
+// Configure applies config to the linter. Unknown rules are reported as an error
+func (l *Linter) Configure(config *LintConfig) error {
+	for id, rc := range config.Rules {
+		if rc.Enabled != nil {
+			if err := l.Enable(id, *rc.Enabled); err != nil {
+				return err
+			}
+		}
+		if rc.Severity != nil {
+			if err := l.SetSeverity(id, *rc.Severity); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Lint runs all enabled rules against config. Findings on lines with or right after
+// '# tfparser:ignore rule-id, ...' comment are dropped, comment without rule ids drops
+// findings of all rules. Findings are sorted by position
+func (l *Linter) Lint(config *TFconfig) []Finding {
+	var findings []Finding
+	for _, r := range l.Rules() {
+		if l.disabled[r.ID()] {
+			continue
+		}
+		for _, f := range r.Check(config) {
+			f.RuleID = r.ID()
+			f.Severity = l.severity[r.ID()]
+			if !config.ignored(f) {
+				findings = append(findings, f)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Pos, findings[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return findings
+}
+
+func (l *Linter) rule(id string) Rule {
+	for _, r := range l.rules {
+		if r.ID() == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// ignored tells if finding f is suppressed with 'tfparser:ignore' comment
+func (c *TFconfig) ignored(f Finding) bool {
+	for _, line := range []int{f.Pos.Line, f.Pos.Line - 1} {
+		rules, exists := c.ignores[ignoreKey{f.Pos.Filename, line}]
+		if !exists {
+			continue
+		}
+		if len(rules) == 0 {
+			return true
+		}
+		for _, id := range rules {
+			if id == f.RuleID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unpinnedModuleSourceRule reports remote modules without fixed version:
+// git and other VCS sources without '?ref=' and registry modules without exact 'version'
+type unpinnedModuleSourceRule struct{}
+
+func (unpinnedModuleSourceRule) ID() string { return "unpinned-module-source" }
+
+func (unpinnedModuleSourceRule) Description() string {
+	return "Remote module sources must be pinned to a git ref or an exact registry version"
+}
+
+func (unpinnedModuleSourceRule) DefaultSeverity() Severity { return SeverityWarning }
+
+func (unpinnedModuleSourceRule) Check(config *TFconfig) []Finding {
+	var findings []Finding
+	for _, name := range sortedModuleNames(config) {
+		m := config.Modules[name]
+		src := m.SourcePath
+		switch {
+		case src == "" || isLocalSource(src):
+		case isRegistrySource(src):
+			version, exists := m.Parameters["version"]
+			if !exists {
+				findings = append(findings, Finding{Pos: m.Pos,
+					Message: fmt.Sprintf("module %#q uses registry source %#q without version", name, src)})
+			} else if !isExactVersion(version) {
+				findings = append(findings, Finding{Pos: m.ParametersPos["version"],
+					Message: fmt.Sprintf("module %#q version %#q is not an exact version", name, version)})
+			}
+		case !strings.Contains(src, "?ref=") && !strings.Contains(src, "&ref="):
+			findings = append(findings, Finding{Pos: m.Pos,
+				Message: fmt.Sprintf("module %#q source %#q is not pinned with '?ref='", name, src)})
+		}
+	}
+	return findings
+}
+
+// isRegistrySource tells if source is a registry module address:
+// 'namespace/name/provider' or 'hostname/namespace/name/provider'
+func isRegistrySource(source string) bool {
+	if strings.Contains(source, "::") || strings.Contains(source, "://") || strings.ContainsAny(source, "?@:") {
+		return false
+	}
+	parts := strings.Split(source, "/")
+	if len(parts) == 4 && strings.Contains(parts[0], ".") {
+		parts = parts[1:]
+	}
+	if len(parts) != 3 {
+		return false
+	}
+	// github.com/org/repo and bitbucket.org/org/repo are VCS shorthands
+	return !strings.Contains(parts[0], ".")
+}
+
+func isExactVersion(version string) bool {
+	v := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(version), "="))
+	return v != "" && !strings.ContainsAny(v, "<>~!,* ")
+}
+
+// duplicateModuleParametersRule reports parameters which have the same literal value as in another
+// module call with the same source, which usually is a copy and paste mistake. Version of the source
+// is expected to be the same and is not reported, as well as values shared with references, e.g.
+// 'region = var.region'
+type duplicateModuleParametersRule struct{}
+
+func (duplicateModuleParametersRule) ID() string { return "duplicate-module-parameters" }
+
+func (duplicateModuleParametersRule) Description() string {
+	return "Module calls with the same source must not repeat literal values of parameters"
+}
+
+func (duplicateModuleParametersRule) DefaultSeverity() Severity { return SeverityWarning }
+
+func (duplicateModuleParametersRule) Check(config *TFconfig) []Finding {
+	var findings []Finding
+	seen := make(map[string]string) // source, parameter and value -> first module call name
+	for _, name := range sortedModuleNames(config) {
+		m := config.Modules[name]
+		for _, param := range sortedStringKeys(m.Parameters) {
+			value, literal := literalParameterValue(m.expr(param))
+			if param == "version" || !literal {
+				continue
+			}
+			key, _ := json.Marshal([]string{m.SourcePath, param, value})
+			first, exists := seen[string(key)]
+			if !exists {
+				seen[string(key)] = name
+				continue
+			}
+			findings = append(findings, Finding{Pos: m.ParametersPos[param],
+				Message: fmt.Sprintf("parameter %#q of module %#q has the same value as in module %#q with the same source", param, name, first)})
+		}
+	}
+	return findings
+}
+
+// literalParameterValue returns module parameter expr formatted the same way regardless of how it
+// is written, if it is a literal, i.e. it does not refer to anything
+func literalParameterValue(expr string) (string, bool) {
+	if len(findReferences(expr)) > 0 {
+		return "", false
+	}
+	v, err := Eval(expr, nil)
+	if err != nil || isUnknown(v) {
+		return "", false
+	}
+	return FormatValue(v), true
+}
+
+// unusedProviderRule reports aliased provider configurations which are neither passed
+// into any module nor referenced by any resource or data source
+type unusedProviderRule struct{}
+
+func (unusedProviderRule) ID() string { return "unused-provider" }
+
+func (unusedProviderRule) Description() string {
+	return "Aliased provider configurations must be used by a module call, resource or data source"
+}
+
+func (unusedProviderRule) DefaultSeverity() Severity { return SeverityWarning }
+
+func (unusedProviderRule) Check(config *TFconfig) []Finding {
+	used := make(map[string]bool)
+	for _, m := range config.Modules {
+		for _, provName := range m.Providers {
+			used[provName] = true
+		}
+	}
+	for _, obj := range config.Objects {
+		for _, ref := range obj.References {
+			used[ref] = true
+		}
+	}
+	var findings []Finding
+	addrs := make([]string, 0, len(config.Providers))
+	for addr := range config.Providers {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	for _, addr := range addrs {
+		p := config.Providers[addr]
+		// default provider configurations are used implicitly
+		if p.Alias == "" || used[addr] {
+			continue
+		}
+		findings = append(findings, Finding{Pos: p.Pos,
+			Message: fmt.Sprintf("provider configuration %#q is not used", addr)})
+	}
+	return findings
+}
+
+// hardcodedRegionRule reports module parameters with AWS region names as values,
+// regions are expected to come from provider configurations or variables
+type hardcodedRegionRule struct{}
+
+func (hardcodedRegionRule) ID() string { return "hardcoded-region" }
+
+func (hardcodedRegionRule) Description() string {
+	return "Module parameters must not have hard-coded region names"
+}
+
+func (hardcodedRegionRule) DefaultSeverity() Severity { return SeverityInfo }
+
+func (hardcodedRegionRule) Check(config *TFconfig) []Finding {
+	var findings []Finding
+	for _, name := range sortedModuleNames(config) {
+		m := config.Modules[name]
+		for _, par := range sortedStringKeys(m.Parameters) {
+			if isRegion(m.Parameters[par]) {
+				findings = append(findings, Finding{Pos: m.ParametersPos[par],
+					Message: fmt.Sprintf("module %#q parameter %#q has hard-coded region %#q", name, par, m.Parameters[par])})
+			}
+		}
+	}
+	return findings
+}
+
+var regionDirections = []string{"north", "south", "east", "west", "central",
+	"northeast", "northwest", "southeast", "southwest"}
+
+// isRegion tells if s looks like AWS region name, e.g. 'us-east-1' or 'us-gov-west-1'
+func isRegion(s string) bool {
+	parts := strings.Split(s, "-")
+	if len(parts) == 4 && parts[1] == "gov" {
+		parts = append(parts[:1], parts[2:]...)
+	}
+	if len(parts) != 3 || len(parts[0]) != 2 || len(parts[2]) != 1 || parts[2][0] < '0' || parts[2][0] > '9' {
+		return false
+	}
+	for _, d := range regionDirections {
+		if parts[1] == d {
+			return true
+		}
+	}
+	return false
+}
+
+// movedAddressRule reports 'moved' blocks whose 'to' address is not declared, or whose 'from'
+// address is still declared. Objects inside of called modules are checked on module call level
+type movedAddressRule struct{}
+
+func (movedAddressRule) ID() string { return "moved-address" }
+
+func (movedAddressRule) Description() string {
+	return "Moved blocks must point from an address which no longer exists to a declared one"
+}
+
+func (movedAddressRule) DefaultSeverity() Severity { return SeverityError }
+
+func (movedAddressRule) Check(config *TFconfig) []Finding {
+	var findings []Finding
+	for _, m := range config.Moved {
+		fromModule, fromIndex, fromRest := splitModuleAddress(m.From)
+		toModule, _, toRest := splitModuleAddress(m.To)
+		fromCall, toCall := fromModule != "" && fromRest == "", toModule != "" && toRest == ""
+		switch {
+		case fromCall != toCall:
+			findings = append(findings, Finding{Pos: m.Pos,
+				Message: fmt.Sprintf("moved from %#q to %#q: both addresses must be either module calls or resources", m.From, m.To)})
+			continue
+		case toModule != "" && config.Modules[toModule] == nil:
+			findings = append(findings, Finding{Pos: m.Pos,
+				Message: fmt.Sprintf("moved to %#q: module %#q is not declared", m.To, toModule)})
+		case toModule == "" && config.Objects[toRest] == nil:
+			findings = append(findings, Finding{Pos: m.Pos,
+				Message: fmt.Sprintf("moved to %#q: %#q is not declared", m.To, toRest)})
+		}
+		// object may stay declared if only its index changes, e.g. when count is added
+		if fromIndex != "" || fromModule == toModule && fromRest == toRest {
+			continue
+		}
+		switch {
+		case fromCall && config.Modules[fromModule] != nil:
+			findings = append(findings, Finding{Pos: m.Pos,
+				Message: fmt.Sprintf("moved from %#q: module %#q is still declared", m.From, fromModule)})
+		case fromModule == "" && config.Objects[fromRest] != nil:
+			findings = append(findings, Finding{Pos: m.Pos,
+				Message: fmt.Sprintf("moved from %#q: %#q is still declared", m.From, fromRest)})
+		}
+	}
+	return findings
+}
+
+// splitModuleAddress splits address into the name of module call, its index and the rest of the
+// address, e.g. 'module.vpc["a"].aws_subnet.x' into 'vpc', '["a"]' and 'aws_subnet.x'. For objects of
+// the root module name is empty and index is the one of the object, e.g. 'aws_instance.web[0]' is
+// split into empty name, '[0]' and 'aws_instance.web'
+func splitModuleAddress(address string) (module, index, rest string) {
+	address = strings.TrimSpace(address)
+	if strings.HasPrefix(address, "module.") {
+		address = address[len("module."):]
+		end := strings.IndexAny(address, ".[")
+		if end < 0 {
+			return address, "", ""
+		}
+		module, address = address[:end], address[end:]
+		if strings.HasPrefix(address, "[") {
+			closing := strings.IndexByte(address, ']')
+			if closing < 0 {
+				closing = len(address) - 1
+			}
+			index, address = address[:closing+1], address[closing+1:]
+		}
+		address = strings.TrimPrefix(address, ".")
+	} else if i := strings.IndexByte(address, '['); i >= 0 {
+		index, address = address[i:], address[:i]
+	}
+	return module, index, address
+}
@@ -0,0 +1,121 @@
+package tfparser
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	config, err := ParseFile("testdata/lint/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	expected := []struct {
+		rule string
+		line int
+	}{
+		{"unused-provider", 5},
+		{"unpinned-module-source", 23},
+		{"hardcoded-region", 25},
+		{"unpinned-module-source", 30},
+		{"duplicate-module-parameters", 31},
+		{"unpinned-module-source", 35},
+		{"hardcoded-region", 45},
+		{"duplicate-module-parameters", 51},
+	}
+	findings := NewLinter().Lint(config)
+	if len(findings) != len(expected) {
+		t.Fatalf("Unexpected number of findings %v, expected %v: %v", len(findings), len(expected), findings)
+	}
+	for i, e := range expected {
+		if findings[i].RuleID != e.rule || findings[i].Pos.Line != e.line {
+			t.Fatalf("Unexpected finding %v, expected %v at line %v", findings[i], e.rule, e.line)
+		}
+	}
+}
+
+func TestLintConfigure(t *testing.T) {
+	config, err := ParseFile("testdata/lint/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	l := NewLinter()
+	disabled, severity := false, SeverityError
+	err = l.Configure(&LintConfig{map[string]RuleConfig{
+		"unpinned-module-source": {Enabled: &disabled},
+		"hardcoded-region":       {Severity: &severity},
+	}})
+	if err != nil {
+		t.Fatalf("Configure returned an error, %v", err)
+	}
+	for _, f := range l.Lint(config) {
+		if f.RuleID == "unpinned-module-source" {
+			t.Fatalf("Disabled rule reported a finding: %v", f)
+		}
+		if f.RuleID == "hardcoded-region" && f.Severity != SeverityError {
+			t.Fatalf("Severity of rule 'hardcoded-region' was not changed: %v", f)
+		}
+	}
+	err = l.Configure(&LintConfig{map[string]RuleConfig{"no-such-rule": {Enabled: &disabled}}})
+	if err == nil {
+		t.Fatal("Configure did not return an error for unknown rule")
+	}
+}
+
+func TestIsRegistrySource(t *testing.T) {
+	tests := map[string]bool{
+		"terraform-aws-modules/vpc/aws":                         true,
+		"app.terraform.io/example-corp/k8s-cluster/azurerm":     true,
+		"github.com/hashicorp/example":                          false,
+		"git::https://example.com/vpc.git":                      false,
+		"../../modules/vpc":                                     false,
+		"s3::https://s3-eu-west-1.amazonaws.com/bucket/vpc.zip": false,
+	}
+	for src, expected := range tests {
+		if isRegistrySource(src) != expected {
+			t.Fatalf("isRegistrySource(%#q) returned %v, expected %v", src, !expected, expected)
+		}
+	}
+}
+
+func TestLintMovedAddress(t *testing.T) {
+	config, err := ParseFile("testdata/meta/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	l := NewLinter()
+	for _, r := range l.Rules() {
+		l.Enable(r.ID(), r.ID() == "moved-address")
+	}
+	expected := []string{
+		"moved to `module.missing`: module `missing` is not declared",
+		"moved from `module.vpc`: module `vpc` is still declared",
+		"moved from `module.old` to `aws_instance.web`: both addresses must be either module calls or resources",
+	}
+	findings := l.Lint(config)
+	if len(findings) != len(expected) {
+		t.Fatalf("Unexpected findings %v, expected %v", findings, expected)
+	}
+	for i, f := range findings {
+		if f.Message != expected[i] || f.Severity != SeverityError {
+			t.Errorf("Unexpected finding %v, expected %#q", f, expected[i])
+		}
+	}
+}
+
+func TestSplitModuleAddress(t *testing.T) {
+	tests := []struct{ address, module, index, rest string }{
+		{"module.vpc", "vpc", "", ""},
+		{`module.vpc["a"]`, "vpc", `["a"]`, ""},
+		{"module.vpc[0].aws_subnet.x", "vpc", "[0]", "aws_subnet.x"},
+		{"module.vpc.module.subnets", "vpc", "", "module.subnets"},
+		{"aws_instance.web[0]", "", "[0]", "aws_instance.web"},
+		{"data.aws_ami.ubuntu", "", "", "data.aws_ami.ubuntu"},
+	}
+	for _, test := range tests {
+		module, index, rest := splitModuleAddress(test.address)
+		if module != test.module || index != test.index || rest != test.rest {
+			t.Errorf("splitModuleAddress(%#q) = %#q, %#q, %#q, expected %#q, %#q, %#q",
+				test.address, module, index, rest, test.module, test.index, test.rest)
+		}
+	}
+}
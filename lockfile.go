@@ -0,0 +1,279 @@
+package tfparser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LockFile is the name of dependency lock file written by 'terraform init'
+const LockFile = ".terraform.lock.hcl"
+
+// defaultRegistry is the hostname of provider sources written without one
+const defaultRegistry = "registry.terraform.io"
+
+// LockedProvider represents 'provider' block of dependency lock file
+type LockedProvider struct {
+	Address     string   `json:"address"` // e.g. 'registry.terraform.io/hashicorp/aws'
+	Version     string   `json:"version"`
+	Constraints string   `json:"constraints,omitempty"` // constraints the version was selected with
+	Hashes      []string `json:"hashes,omitempty"`
+	Pos         Pos      `json:"pos"`
+}
+
+// ParseLockFile parses dependency lock file, see LockFile. Result is keyed by provider address
+func ParseLockFile(filename string) (map[string]*LockedProvider, error) {
+	body, err := ParseBodyFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseLocks(body)
+}
+
+// ParseLock parses dependency lock file content data, see ParseLockFile
+func ParseLock(data []byte, filename string) (map[string]*LockedProvider, error) {
+	body, err := ParseBody(data, filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseLocks(body)
+}
+
+func parseLocks(body *Block) (map[string]*LockedProvider, error) {
+	locks := make(map[string]*LockedProvider)
+	for _, b := range body.Blocks {
+		if b.Type != "provider" {
+			continue
+		}
+		if len(b.Labels) != 1 {
+			return nil, fmt.Errorf("%v: Provider block must have an address", b.Pos)
+		}
+		lock := &LockedProvider{
+			Address:     strings.ToLower(b.Labels[0]),
+			Version:     b.Attributes["version"],
+			Constraints: b.Attributes["constraints"],
+			Pos:         b.Pos,
+		}
+		if lock.Version == "" {
+			return nil, fmt.Errorf("%v: Missing version of provider %#q", b.Pos, lock.Address)
+		}
+		if hashes, exists := b.Attributes["hashes"]; exists {
+			p := newParser(hashes, b.Pos.Filename, &TFconfig{})
+			var err error
+			if lock.Hashes, err = p.popList(); err != nil {
+				return nil, fmt.Errorf("%v: Invalid hashes of provider %#q: %v", b.AttributesPos["hashes"], lock.Address, err)
+			}
+		}
+		locks[lock.Address] = lock
+	}
+	return locks, nil
+}
+
+// ProviderAddress returns fully qualified address of provider source, e.g.
+// 'registry.terraform.io/hashicorp/aws' for 'hashicorp/aws'. Required provider without
+// source is a 'hashicorp' provider with its local name
+func (rp *RequiredProvider) ProviderAddress() string {
+	source := rp.Source
+	if source == "" {
+		source = "hashicorp/" + rp.Name
+	}
+	if strings.Count(source, "/") == 1 {
+		source = defaultRegistry + "/" + source
+	}
+	return strings.ToLower(source)
+}
+
+// LockIssue describes a required provider, which lock file does not satisfy
+type LockIssue struct {
+	Provider string `json:"provider"` // local name of required provider
+	Pos      Pos    `json:"pos"`      // position of required provider declaration
+	Message  string `json:"message"`
+}
+
+func (i LockIssue) String() string {
+	return fmt.Sprintf("%v: provider %#q: %v", i.Pos, i.Provider, i.Message)
+}
+
+// CheckLocks compares versions in locks with version constraints of required providers in config.
+// Required providers which are not locked are reported too, as 'terraform init' must be run for them
+func CheckLocks(config *TFconfig, locks map[string]*LockedProvider) []LockIssue {
+	var issues []LockIssue
+	for _, rp := range config.sortedRequiredProviders() {
+		address := rp.ProviderAddress()
+		lock, exists := locks[address]
+		if !exists {
+			issues = append(issues, LockIssue{rp.Name, rp.Pos, fmt.Sprintf("%#q is not locked", address)})
+			continue
+		}
+		if rp.Version == "" {
+			continue
+		}
+		ok, err := versionMatches(lock.Version, rp.Version)
+		if err != nil {
+			issues = append(issues, LockIssue{rp.Name, rp.Pos, err.Error()})
+		} else if !ok {
+			issues = append(issues, LockIssue{rp.Name, rp.Pos,
+				fmt.Sprintf("locked version %v does not match constraints %#q", lock.Version, rp.Version)})
+		}
+	}
+	return issues
+}
+
+// CheckLocksDir parses terraform config in dirname along with its lock file and checks them with CheckLocks
+func CheckLocksDir(dirname string) ([]LockIssue, error) {
+	config, err := ParseDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	locks, err := ParseLockFile(filepath.Join(dirname, LockFile))
+	if err != nil {
+		return nil, err
+	}
+	return CheckLocks(config, locks), nil
+}
+
+// Finding converts issue to a lint finding of LockFileRuleID rule with error severity
+func (i LockIssue) Finding() Finding {
+	return Finding{LockFileRuleID, SeverityError, i.Pos, fmt.Sprintf("provider %#q: %v", i.Provider, i.Message)}
+}
+
+// LockFileRuleID is the ID of the rule returned by NewLockFileRule
+const LockFileRuleID = "lock-file"
+
+// NewLockFileRule returns lint rule, which reports the same issues as CheckLocks.
+// locks are the parsed lock file of the configuration, see ParseLockFile
+func NewLockFileRule(locks map[string]*LockedProvider) Rule {
+	return lockFileRule{locks}
+}
+
+type lockFileRule struct {
+	locks map[string]*LockedProvider
+}
+
+func (lockFileRule) ID() string { return LockFileRuleID }
+
+func (lockFileRule) Description() string {
+	return "Locked provider versions must match version constraints of required providers"
+}
+
+func (lockFileRule) DefaultSeverity() Severity { return SeverityError }
+
+func (r lockFileRule) Check(config *TFconfig) []Finding {
+	var findings []Finding
+	for _, issue := range CheckLocks(config, r.locks) {
+		findings = append(findings, issue.Finding())
+	}
+	return findings
+}
+
+// version is a parsed version number, e.g. '1.2.3-beta1'
+type version struct {
+	segments   [3]int
+	n          int // number of segments written
+	prerelease string
+}
+
+func parseVersion(s string) (version, error) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		if s[i] == '-' {
+			v.prerelease = s[i+1:]
+			if j := strings.IndexByte(v.prerelease, '+'); j >= 0 {
+				v.prerelease = v.prerelease[:j]
+			}
+		}
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("Invalid version %#q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("Invalid version %#q", s)
+		}
+		v.segments[i] = n
+	}
+	v.n = len(parts)
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 if v is less, equal or greater than w
+func (v version) compare(w version) int {
+	for i := range v.segments {
+		if v.segments[i] != w.segments[i] {
+			if v.segments[i] < w.segments[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.prerelease == w.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case w.prerelease == "":
+		return -1
+	case v.prerelease < w.prerelease:
+		return -1
+	}
+	return 1
+}
+
+// versionMatches tells if version s matches comma separated constraints, e.g. '>= 1.2, ~> 1.4'.
+// As in terraform, prerelease versions match only exact '=' constraints
+func versionMatches(s, constraints string) (bool, error) {
+	v, err := parseVersion(s)
+	if err != nil {
+		return false, err
+	}
+	exact := false
+	for _, c := range strings.Split(constraints, ",") {
+		c = strings.TrimSpace(c)
+		op := c[:len(c)-len(strings.TrimLeft(c, "=!<>~"))]
+		w, err := parseVersion(c[len(op):])
+		if err != nil {
+			return false, fmt.Errorf("Invalid version constraint %#q", c)
+		}
+		cmp := v.compare(w)
+		var ok bool
+		switch op {
+		case "", "=":
+			ok = cmp == 0
+			exact = exact || ok
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "~>":
+			// only the last written segment may increase: '~> 1.2' is '>= 1.2, < 2.0'
+			upper := w
+			upper.prerelease = ""
+			i := w.n - 2
+			if i < 0 {
+				i = 0
+			}
+			upper.segments[i]++
+			for j := i + 1; j < len(upper.segments); j++ {
+				upper.segments[j] = 0
+			}
+			ok = cmp >= 0 && v.compare(upper) < 0
+		default:
+			return false, fmt.Errorf("Invalid version constraint %#q", c)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return v.prerelease == "" || exact, nil
+}
@@ -0,0 +1,89 @@
+package tfparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLockFile(t *testing.T) {
+	locks, err := ParseLockFile("testdata/lockfile/" + LockFile)
+	if err != nil {
+		t.Fatalf("ParseLockFile returned an error, %v", err)
+	}
+	if len(locks) != 3 {
+		t.Fatalf("Unexpected number of locks %v", len(locks))
+	}
+	aws := locks["registry.terraform.io/hashicorp/aws"]
+	expected := &LockedProvider{
+		Address:     "registry.terraform.io/hashicorp/aws",
+		Version:     "5.31.0",
+		Constraints: ">= 4.0.0",
+		Hashes: []string{
+			"h1:ltxyuBWIy9cq0kIKDJH1jeWJy/y7XJLjS4QrsQK4plA=",
+			"zh:0cdb9c2083bf0902442384f7309367791e4640581652dda456f2d6d7abf0de8d",
+		},
+		Pos: Pos{"testdata/lockfile/" + LockFile, 4, 1},
+	}
+	if !reflect.DeepEqual(aws, expected) {
+		t.Errorf("Unexpected lock %+v, expected %+v", aws, expected)
+	}
+
+	if _, err := ParseLock([]byte(`provider "registry.terraform.io/hashicorp/aws" {}`), ""); err == nil {
+		t.Error("ParseLock did not return an error for provider without version")
+	}
+}
+
+func TestCheckLocksDir(t *testing.T) {
+	issues, err := CheckLocksDir("testdata/lockfile")
+	if err != nil {
+		t.Fatalf("CheckLocksDir returned an error, %v", err)
+	}
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.Provider+": "+issue.Message)
+	}
+	expected := []string{
+		"aws: locked version 5.31.0 does not match constraints `~> 4.0`",
+		"github: `registry.terraform.io/integrations/github` is not locked",
+		"tls: locked version 4.0.5 does not match constraints `4.0.4`",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected issues %v, expected %v", got, expected)
+	}
+}
+
+func TestVersionMatches(t *testing.T) {
+	for _, test := range []struct {
+		version     string
+		constraints string
+		matches     bool
+	}{
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "= 1.2.4", false},
+		{"1.2.3", "!= 1.2.3", false},
+		{"1.2.3", ">= 1.2, < 2.0", true},
+		{"2.0.0", ">= 1.2, < 2.0", false},
+		{"1.9.0", "~> 1.2", true},
+		{"2.0.0", "~> 1.2", false},
+		{"1.2.9", "~> 1.2.3", true},
+		{"1.3.0", "~> 1.2.3", false},
+		{"1.2.2", "~> 1.2.3", false},
+		{"3.0.0", "~> 3", true},
+		{"4.0.0", "~> 3", false},
+		{"1.3.0-beta1", ">= 1.2", false},
+		{"1.3.0-beta1", "1.3.0-beta1", true},
+		{"1.3.0-beta1", "< 1.3.0", false},
+	} {
+		matches, err := versionMatches(test.version, test.constraints)
+		if err != nil {
+			t.Errorf("versionMatches(%v, %v) returned an error, %v", test.version, test.constraints, err)
+		} else if matches != test.matches {
+			t.Errorf("versionMatches(%v, %v) returned %v", test.version, test.constraints, matches)
+		}
+	}
+	for _, constraints := range []string{"", ">= x", "=> 1.0", "1.0.0.0"} {
+		if _, err := versionMatches("1.0.0", constraints); err == nil {
+			t.Errorf("versionMatches did not return an error for %#q", constraints)
+		}
+	}
+}
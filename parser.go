@@ -1,6 +1,6 @@
 /*
 Package tfparser provides a parser that allows getting a very specific piece of information from terraform
-configuration (as text, file or directory):
+configuration (as text, reader, file, directory or io/fs filesystem):
 for all modules used in the configuration it reads all parameters and providers passed into module. It also
 reads source path for the module.
 
@@ -10,37 +10,180 @@ package tfparser
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
-	"log"
+	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
+// Pos represents a position in terraform configuration
+type Pos struct {
+	Filename string `json:"filename,omitempty"` // empty when parsing a string
+	Line     int    `json:"line"`               // starting at 1
+	Column   int    `json:"column"`             // starting at 1
+}
+
+func (p Pos) String() string {
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
 // Module represents a call to a module
 type Module struct {
-	Providers  map[string]string
-	Parameters map[string]string
-	SourcePath string
+	Providers     map[string]string `json:"providers"`
+	Parameters    map[string]string `json:"parameters"`
+	SourcePath    string            `json:"source"`
+	Pos           Pos               `json:"pos"`              // position of the 'module' keyword
+	ProvidersPos  map[string]Pos    `json:"providers_pos"`    // positions of provider aliases, keyed as Providers
+	ParametersPos map[string]Pos    `json:"parameters_pos"`   // positions of parameters, keyed as Parameters
+	Blocks        []*Block          `json:"blocks,omitempty"` // nested blocks, e.g. 'lifecycle'
+
+	exprs map[string]string // parameters as they are written, keyed as Parameters
+}
+
+// Block is a block of configuration which is not represented with a specialised type, e.g.
+// 'resource', 'variable', 'ingress' nested in a resource, or 'terraform' with settings like
+// 'backend'. Dynamic block is a block of type 'dynamic' labeled with the type of generated blocks,
+// with 'for_each' attribute and 'content' block
+type Block struct {
+	Type          string            `json:"type"`
+	Labels        []string          `json:"labels,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`     // expressions as they are written, strings unquoted with escapes interpreted
+	AttributesPos map[string]Pos    `json:"attributes_pos,omitempty"` // keyed as Attributes
+	Blocks        []*Block          `json:"blocks,omitempty"`         // nested blocks in order of declaration
+	Pos           Pos               `json:"pos"`                      // position of block type
+
+	exprs map[string]string // attributes as they are written, keyed as Attributes
+}
+
+// Provider represents a provider configuration block
+type Provider struct {
+	Name          string            `json:"name"`
+	Alias         string            `json:"alias,omitempty"`          // empty for default provider configuration
+	Attributes    map[string]string `json:"attributes,omitempty"`     // as in Block, including 'alias'
+	AttributesPos map[string]Pos    `json:"attributes_pos,omitempty"` // keyed as Attributes
+	Blocks        []*Block          `json:"blocks,omitempty"`         // nested blocks, e.g. 'assume_role'
+	Pos           Pos               `json:"pos"`
+}
+
+// Address returns provider address as it is used in module 'providers' map: 'name' or 'name.alias'
+func (p *Provider) Address() string {
+	if p.Alias == "" {
+		return p.Name
+	}
+	return p.Name + "." + p.Alias
+}
+
+// RequiredProvider represents an entry of 'required_providers' block in 'terraform' block
+type RequiredProvider struct {
+	Name                 string   `json:"name"`
+	Source               string   `json:"source,omitempty"`
+	Version              string   `json:"version,omitempty"`
+	ConfigurationAliases []string `json:"configuration_aliases,omitempty"` // as 'name.alias'
+	Pos                  Pos      `json:"pos"`
+}
+
+// Object is a named object of configuration which can be referenced from expressions:
+// module call, resource, data source, variable, local value or output
+type Object struct {
+	Address    string   `json:"address"`              // e.g. 'module.vpc', 'aws_vpc.main', 'data.aws_ami.ubuntu', 'var.region', 'local.name', 'output.vpc_id'
+	References []string `json:"references,omitempty"` // traversals found in object's expressions, e.g. 'module.vpc.vpc_id'
+	Pos        Pos      `json:"pos"`
+}
+
+// Moved represents a 'moved' block, which records that an object was renamed or moved.
+// Addresses are checked by lint rule 'moved-address'
+type Moved struct {
+	From string `json:"from"` // address, e.g. 'module.old'
+	To   string `json:"to"`
+	Pos  Pos    `json:"pos"`
+}
+
+// Import represents an 'import' block, which imports existing infrastructure into an object
+type Import struct {
+	To       string `json:"to"`                 // address of the object
+	ID       string `json:"id"`                 // id of imported infrastructure, unquoted if it is a string
+	Provider string `json:"provider,omitempty"` // provider configuration address, e.g. 'aws.alice'
+	ForEach  string `json:"for_each,omitempty"` // expression, as it is written
+	Pos      Pos    `json:"pos"`
+}
+
+// Removed represents a 'removed' block, which removes an object from the state
+type Removed struct {
+	From    string `json:"from"`    // address of the object
+	Destroy bool   `json:"destroy"` // 'destroy' of 'lifecycle' block, true if not set
+	Pos     Pos    `json:"pos"`
+}
+
+// Check represents a 'check' block
+type Check struct {
+	Name       string       `json:"name"`
+	Assertions []*Assertion `json:"assertions,omitempty"`
+	Pos        Pos          `json:"pos"`
+}
+
+// Assertion represents an 'assert' block of a check
+type Assertion struct {
+	Condition    string `json:"condition"`     // expression, as it is written
+	ErrorMessage string `json:"error_message"` // unquoted if it is a string
+	Pos          Pos    `json:"pos"`
 }
 
 // TFconfig represents a tf configiration
 type TFconfig struct {
-	Modules map[string]*Module
+	Modules           map[string]*Module           `json:"modules,omitempty"`
+	Providers         map[string]*Provider         `json:"providers,omitempty"`          // keyed by provider address, see Provider.Address
+	RequiredProviders map[string]*RequiredProvider `json:"required_providers,omitempty"` // keyed by provider local name
+	Objects           map[string]*Object           `json:"objects,omitempty"`            // keyed by object address
+	Moved             []*Moved                     `json:"moved,omitempty"`              // in order of declaration
+	Imports           []*Import                    `json:"imports,omitempty"`            // in order of declaration
+	Removed           []*Removed                   `json:"removed,omitempty"`            // in order of declaration
+	Checks            map[string]*Check            `json:"checks,omitempty"`             // keyed by check name
+	Blocks            []*Block                     `json:"blocks,omitempty"`             // other top level blocks in order of declaration, every 'terraform' block without its 'required_providers'
+
+	ignores map[ignoreKey][]string // rules ignored with 'tfparser:ignore' comments, empty list to ignore all
+	locals  map[string]string      // local values as they are written, keyed by name
+}
+
+// ignoreKey is the line of 'tfparser:ignore' comment
+type ignoreKey struct {
+	filename string
+	line     int
 }
 
 type parser struct {
-	data          string    // tf file(s) as string
-	i             int       // index in data
-	config        *TFconfig // TFconfig struct we are building
-	state         state     // FSM state
-	err           error
-	curModName    string // name of the module we are parsing
-	curModParName string // If we are parsing module parametes, what it name is
+	data            string    // tf file(s) as string
+	filename        string    // name of the file data was read from, used for positions
+	i               int       // index in data
+	posIndex        int       // index in data pos was counted till
+	posLine         int       // number of newlines in data before posIndex
+	posLineStart    int       // index of the start of the line posIndex is at
+	config          *TFconfig // TFconfig struct we are building
+	state           state     // FSM state
+	err             error
+	curModName      string    // name of the module we are parsing
+	curModParName   string    // If we are parsing module parametes, what it name is
+	curModParPos    Pos       // position of the module parameter we are parsing
+	curModPos       Pos       // position of the module we are parsing
+	curProvider     *Provider // provider configuration we are parsing
+	curTerraform    *Block    // terraform block we are parsing
+	curModBodyStart int       // index in data where body of the module we are parsing starts
+	header          []string  // type and labels of top level block we are reading
+	headerPos       Pos       // position of top level block we are reading
+}
+
+func newParser(data, filename string, config *TFconfig) *parser {
+	return &parser{data: data, filename: filename, config: config, state: stateTop}
 }
 
 // ParseString parses a string with tf configurarion
 func ParseString(s string) (*TFconfig, error) {
-	return (&parser{strings.TrimSpace(s), 0, &TFconfig{}, stateTop, nil, "", ""}).parse()
+	return newParser(s, "", &TFconfig{}).parse()
 }
 
 // ParseFile parses terraform config from file filename
@@ -49,27 +192,78 @@ func ParseFile(filename string) (*TFconfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	return ParseString(string(content))
+	return newParser(string(content), filename, &TFconfig{}).parse()
+}
+
+// ParseReader parses terraform config read from r, filename is used in positions only
+func ParseReader(r io.Reader, filename string) (*TFconfig, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return newParser(string(content), filename, &TFconfig{}).parse()
+}
+
+// ParseFS parses terraform config in all *.tf files in a dir dirname of filesystem fsys, e.g.
+// embed.FS or zip.Reader. As in ParseDir, subdirectories are not read. Positions refer to
+// slash separated paths in fsys
+func ParseFS(fsys fs.FS, dirname string) (*TFconfig, error) {
+	return parseFS(fsys, dirname, func(name string) string { return name })
 }
 
 // ParseDir parses terraform config in all *.tf files in a dir dirname
 func ParseDir(dirname string) (*TFconfig, error) {
-	dirList, err := ioutil.ReadDir(dirname)
+	return parseFS(os.DirFS(dirname), ".", func(name string) string {
+		return filepath.Join(dirname, filepath.FromSlash(name))
+	})
+}
+
+// parseFS parses *.tf files in a dir dirname of fsys, filename converts paths in fsys to names
+// of files used in positions and errors
+func parseFS(fsys fs.FS, dirname string, filename func(string) string) (*TFconfig, error) {
+	dirList, err := fs.ReadDir(fsys, dirname)
 	if err != nil {
-		return nil, err
+		return nil, hostPathError(err, filename)
 	}
-	var s string
+	var names []string
 	for _, f := range dirList {
+		if !f.IsDir() {
+			names = append(names, path.Join(dirname, f.Name()))
+		}
+	}
+	config, err := parseFiles(names, func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}, filename)
+	return config, hostPathError(err, filename)
+}
+
+// hostPathError converts path of fs.PathError err with filename
+func hostPathError(err error, filename func(string) string) error {
+	if pe, ok := err.(*fs.PathError); ok {
+		return &fs.PathError{Op: pe.Op, Path: filename(pe.Path), Err: pe.Err}
+	}
+	return err
+}
+
+// parseFiles parses *.tf files among names, which must not be directories, in order of names.
+// All files share the same config, so duplicates are detected across files. read returns content
+// of a file, filename converts its name to the one used in positions
+func parseFiles(names []string, read func(string) ([]byte, error), filename func(string) string) (*TFconfig, error) {
+	config := &TFconfig{}
+	for _, name := range names {
 		// read only *.tf file
-		if strings.HasSuffix(f.Name(), ".tf") {
-			c, err := ioutil.ReadFile(filepath.Join(dirname, f.Name()))
-			if err != nil {
-				return nil, err
-			}
-			s += "\n" + string(c)
+		if !strings.HasSuffix(name, ".tf") {
+			continue
+		}
+		c, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := newParser(string(c), filename(name), config).parse(); err != nil {
+			return nil, err
 		}
 	}
-	return ParseString(s)
+	return config, nil
 }
 
 func (p *parser) parse() (*TFconfig, error) {
@@ -80,7 +274,6 @@ func (p *parser) parse() (*TFconfig, error) {
 		}
 		err := p.parseTopLevel()
 		if err != nil {
-			p.logError()
 			return nil, err
 		}
 	}
@@ -93,12 +286,11 @@ func (p *parser) validate() error {
 	if p.curModParName != "" {
 		return fmt.Errorf("Did not find the value for %v param of module %v", p.curModParName, p.curModName)
 	}
-	return nil
-}
-
-func (p *parser) logError() {
-	if p.err == nil {
-		return
+	if p.curProvider != nil {
+		return fmt.Errorf("Did not find the closing curly brace when parsing provider %v", p.curProvider.Name)
+	}
+	if p.state != stateTop || len(p.header) > 0 {
+		return fmt.Errorf("Unexpected end of configuration")
 	}
-	log.Fatal(fmt.Sprintln(p.err))
+	return nil
 }
@@ -1,8 +1,13 @@
 package tfparser
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 var testTFCode = `
@@ -133,6 +138,76 @@ func TestParseDir(t *testing.T) {
 		t.Fatalf("ParseDir returned an error, %v", err)
 	}
 	assertTestingConfig1(config, t)
+	if pos := config.Modules["module1"].Pos; pos.Filename != filepath.Join("testdata", "tf", "main.tf") {
+		t.Errorf("Unexpected position %v", pos)
+	}
+
+	// directories named like configuration files are not read
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "nested.tf"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(`module "a" { source = "./a" }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = ParseDir(dir)
+	if err != nil {
+		t.Fatalf("ParseDir returned an error, %v", err)
+	}
+	if len(config.Modules) != 1 || config.Modules["a"] == nil {
+		t.Errorf("Unexpected modules %v", config.Modules)
+	}
+	missing := filepath.Join(dir, "missing")
+	if _, err := ParseDir(missing); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("ParseDir returned an unexpected error for missing directory, %v", err)
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	config, err := ParseFS(os.DirFS("testdata"), "tf")
+	if err != nil {
+		t.Fatalf("ParseFS returned an error, %v", err)
+	}
+	assertTestingConfig1(config, t)
+	if pos := config.Modules["module1"].Pos; pos.Filename != "tf/main.tf" {
+		t.Errorf("Unexpected position %v", pos)
+	}
+
+	fsys := fstest.MapFS{
+		"live/a.tf":        {Data: []byte(`module "a" { source = "./a" }`)},
+		"live/b.tf":        {Data: []byte(`module "a" { source = "./b" }`)},
+		"live/nested/c.tf": {Data: []byte(`module "c" { source = "./c" }`)},
+	}
+	if _, err := ParseFS(fsys, "live"); err == nil {
+		t.Error("ParseFS did not return an error for module duplicated across files")
+	}
+	delete(fsys, "live/b.tf")
+	config, err = ParseFS(fsys, "live")
+	if err != nil {
+		t.Fatalf("ParseFS returned an error, %v", err)
+	}
+	if len(config.Modules) != 1 || config.Modules["a"] == nil {
+		t.Errorf("Unexpected modules %v", config.Modules)
+	}
+	if _, err := ParseFS(fsys, "missing"); err == nil {
+		t.Error("ParseFS did not return an error for missing directory")
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	f, err := os.Open("testdata/tf/main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	config, err := ParseReader(f, "main.tf")
+	if err != nil {
+		t.Fatalf("ParseReader returned an error, %v", err)
+	}
+	assertTestingConfig1(config, t)
+	if pos := config.Modules["module1"].Pos; pos.Filename != "main.tf" {
+		t.Errorf("Unexpected position %v", pos)
+	}
 }
 
 func assertTestingConfig1(config *TFconfig, t *testing.T) {
@@ -158,3 +233,142 @@ func assertTestingConfig1(config *TFconfig, t *testing.T) {
 		t.Fatalf("provider 'aws.alice' alias is %#q, expected 'aws.us-east-1'", p1)
 	}
 }
+
+func TestModuleExpressionParameters(t *testing.T) {
+	config, err := ParseString(`
+variable "module" {
+  default = "not a module"
+}
+module "vpc" {
+  source = "../../modules/vpc"
+  azs    = ["us-east-1a", "us-east-1b"]
+  name   = join("-", [var.env, "vpc"]) # trailing comment
+  tags   = {
+    Name = "vpc"
+  }
+}`)
+	if err != nil {
+		t.Fatalf("parser returned error %v", err)
+	}
+	if len(config.Modules) != 1 {
+		t.Fatalf("Unexpected number of modules parsed: %v", len(config.Modules))
+	}
+	expected := map[string]string{
+		"azs":  `["us-east-1a", "us-east-1b"]`,
+		"name": `join("-", [var.env, "vpc"])`,
+		"tags": "{\n    Name = \"vpc\"\n  }",
+	}
+	for name, value := range expected {
+		if v := config.Modules["vpc"].Parameters[name]; v != value {
+			t.Fatalf("Unexpected value of parameter %#q: %#q, expected %#q", name, v, value)
+		}
+	}
+}
+
+func TestParseMetaBlocks(t *testing.T) {
+	config, err := ParseFile("testdata/meta/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	if len(config.Moved) != 4 {
+		t.Fatalf("Unexpected number of moved blocks %v, expected 4", len(config.Moved))
+	}
+	if m := config.Moved[0]; m.From != "module.network" || m.To != "module.vpc" || m.Pos.Line != 10 {
+		t.Errorf("Unexpected moved block %+v", m)
+	}
+	if len(config.Imports) != 1 {
+		t.Fatalf("Unexpected number of import blocks %v, expected 1", len(config.Imports))
+	}
+	if i := config.Imports[0]; i.To != "aws_instance.web[1]" || i.ID != "i-0123456789" || i.Provider != "aws.alice" {
+		t.Errorf("Unexpected import block %+v", i)
+	}
+	if len(config.Removed) != 1 || config.Removed[0].From != "module.legacy" || config.Removed[0].Destroy {
+		t.Errorf("Unexpected removed blocks %+v", config.Removed)
+	}
+	check, exists := config.Checks["health"]
+	if !exists {
+		t.Fatal("Check 'health' was not found")
+	}
+	if len(check.Assertions) != 1 || check.Assertions[0].ErrorMessage != `Service "web" is down` ||
+		check.Assertions[0].Condition != "data.http.status.status_code == 200" {
+		t.Errorf("Unexpected assertions %+v", check.Assertions)
+	}
+	// the rest of configuration is still parsed
+	if config.Modules["vpc"] == nil || config.Objects["aws_instance.web"] == nil {
+		t.Errorf("Module or resource was not parsed")
+	}
+
+	for _, tf := range []string{
+		"moved {\n  from = module.a\n}\n",
+		"moved \"label\" {\n  from = module.a\n  to = module.b\n}\n",
+		"check \"a\" {\n}\ncheck \"a\" {\n}\n",
+	} {
+		if _, err := ParseString(tf); err == nil {
+			t.Errorf("ParseString did not return an error for %#q", tf)
+		}
+	}
+}
+
+func TestParseGenericBlocks(t *testing.T) {
+	config, err := ParseFile("testdata/blocks/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	if len(config.Blocks) != 3 {
+		t.Fatalf("Unexpected number of top level blocks %v, expected 3", len(config.Blocks))
+	}
+	sg := config.Blocks[0]
+	if sg.Type != "resource" || !reflect.DeepEqual(sg.Labels, []string{"aws_security_group", "web"}) {
+		t.Fatalf("Unexpected block %v %v", sg.Type, sg.Labels)
+	}
+	if sg.Attributes["name"] != "web" || sg.AttributesPos["name"].Line != 11 {
+		t.Errorf("Unexpected attribute 'name' %#q at %v", sg.Attributes["name"], sg.AttributesPos["name"])
+	}
+	var types []string
+	for _, b := range sg.Blocks {
+		types = append(types, strings.Join(append([]string{b.Type}, b.Labels...), " "))
+	}
+	if expected := []string{"ingress", "dynamic ingress", "lifecycle"}; !reflect.DeepEqual(types, expected) {
+		t.Fatalf("Unexpected nested blocks %v, expected %v", types, expected)
+	}
+	dynamic := sg.Blocks[1]
+	if dynamic.Attributes["for_each"] != "var.ports" || len(dynamic.Blocks) != 1 || dynamic.Blocks[0].Type != "content" ||
+		dynamic.Blocks[0].Attributes["from_port"] != "ingress.value" {
+		t.Errorf("Unexpected dynamic block %+v", dynamic)
+	}
+	if v := config.Blocks[1]; v.Type != "variable" || v.Attributes["default"] != "[80, 8080]" {
+		t.Errorf("Unexpected variable block %+v", v)
+	}
+	// references are still collected from generic blocks
+	if refs := config.Objects["aws_security_group.web"].References; !reflect.DeepEqual(refs, []string{"var.ports"}) {
+		t.Errorf("Unexpected references %v", refs)
+	}
+
+	tf := config.Blocks[2]
+	if tf.Type != "terraform" || tf.Attributes["required_version"] != ">= 1.0" || len(tf.Blocks) != 1 {
+		t.Fatalf("Unexpected terraform block %+v", tf)
+	}
+	backend := tf.Blocks[0]
+	if backend.Type != "backend" || backend.Labels[0] != "s3" || backend.Attributes["key"] != `app/"main".tfstate` {
+		t.Errorf("Unexpected backend block %+v", backend)
+	}
+	if rp := config.RequiredProviders["aws"]; rp == nil || rp.Version != "~> 5.0" {
+		t.Errorf("Unexpected required provider %+v", rp)
+	}
+
+	p := config.Providers["aws.assumed"]
+	if p == nil || len(p.Blocks) != 1 || p.Blocks[0].Type != "assume_role" {
+		t.Fatalf("Nested block of provider was not read: %+v", p)
+	}
+	if p.Attributes["region"] != "us-east-1" || p.AttributesPos["region"].Line != 2 {
+		t.Errorf("Unexpected attribute 'region' %#q at %v", p.Attributes["region"], p.AttributesPos["region"])
+	}
+	m := config.Modules["legacy"]
+	if len(m.Blocks) != 1 || m.Blocks[0].Type != "experimental" || m.Blocks[0].Labels[0] != "feature" ||
+		m.Blocks[0].Attributes["enabled"] != "true" {
+		t.Fatalf("Nested block of module was not read: %+v", m.Blocks)
+	}
+	if len(m.Parameters) != 0 {
+		t.Errorf("Nested block was read as module parameter: %v", m.Parameters)
+	}
+}
@@ -0,0 +1,126 @@
+package tfparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Plan is a saved plan in the format of 'terraform show -json'. Only changes of resources are read
+type Plan struct {
+	FormatVersion    string            `json:"format_version"`
+	TerraformVersion string            `json:"terraform_version"`
+	ResourceChanges  []*ResourceChange `json:"resource_changes"`
+}
+
+// ResourceChange is a planned change of resource instance
+type ResourceChange struct {
+	Address       string      `json:"address"`                  // e.g. 'module.vpc["a"].aws_subnet.this[0]'
+	ModuleAddress string      `json:"module_address,omitempty"` // e.g. 'module.vpc["a"]', empty for root module
+	Mode          string      `json:"mode"`                     // 'managed' or 'data'
+	Type          string      `json:"type"`
+	Name          string      `json:"name"`
+	Index         interface{} `json:"index,omitempty"`
+	ProviderName  string      `json:"provider_name"`
+	Change        struct {
+		Actions []string `json:"actions"` // e.g. ['create'] or ['delete', 'create'] for replacement
+	} `json:"change"`
+}
+
+// ParsePlan parses output of 'terraform show -json' for a saved plan
+func ParsePlan(data []byte) (*Plan, error) {
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("Unable to parse plan: %v", err)
+	}
+	if plan.FormatVersion == "" {
+		return nil, fmt.Errorf("Unable to parse plan: missing format_version")
+	}
+	if major := strings.SplitN(plan.FormatVersion, ".", 2)[0]; major != "0" && major != "1" {
+		return nil, fmt.Errorf("Unsupported plan format version %v", plan.FormatVersion)
+	}
+	return &plan, nil
+}
+
+// ParsePlanFile reads filename and parses it with ParsePlan
+func ParsePlanFile(filename string) (*Plan, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := ParsePlan(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	return plan, nil
+}
+
+// ModuleChanges are planned changes of resources in instances of a module call, including
+// modules nested in it. Counts are the ones of terraform plan summary: replacement is counted both
+// as add and destroy, reads of data sources are not counted
+type ModuleChanges struct {
+	Module    string            `json:"module"` // name of module call
+	Pos       Pos               `json:"pos"`    // position of module call
+	Add       int               `json:"add"`
+	Change    int               `json:"change"`
+	Destroy   int               `json:"destroy"`
+	Resources []*ResourceChange `json:"resources"` // counted changes in order of plan
+}
+
+// Summary returns counts of changes as terraform prints them, e.g. '3 to add, 0 to change, 1 to destroy'
+func (c *ModuleChanges) Summary() string {
+	return fmt.Sprintf("%v to add, %v to change, %v to destroy", c.Add, c.Change, c.Destroy)
+}
+
+// MapPlan maps changes of plan to module calls of root module config, which produced them.
+// Result is sorted by module name and contains only module calls with changes. Counted changes
+// which do not belong to any module call, e.g. ones of root module resources, are returned separately
+func MapPlan(config *TFconfig, plan *Plan) ([]*ModuleChanges, []*ResourceChange) {
+	modules := make(map[string]*ModuleChanges)
+	var unmapped []*ResourceChange
+	for _, rc := range plan.ResourceChanges {
+		add, change, destroy := countActions(rc.Change.Actions)
+		if add+change+destroy == 0 {
+			continue
+		}
+		name, _, _ := splitModuleAddress(rc.ModuleAddress)
+		m, exists := config.Modules[name]
+		if !exists {
+			unmapped = append(unmapped, rc)
+			continue
+		}
+		mc := modules[name]
+		if mc == nil {
+			mc = &ModuleChanges{Module: name, Pos: m.Pos}
+			modules[name] = mc
+		}
+		mc.Add += add
+		mc.Change += change
+		mc.Destroy += destroy
+		mc.Resources = append(mc.Resources, rc)
+	}
+
+	result := make([]*ModuleChanges, 0, len(modules))
+	for _, mc := range modules {
+		result = append(result, mc)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Module < result[j].Module })
+	return result, unmapped
+}
+
+// countActions returns numbers of added, changed and destroyed instances for actions of a change
+func countActions(actions []string) (add, change, destroy int) {
+	for _, action := range actions {
+		switch action {
+		case "create":
+			add++
+		case "update":
+			change++
+		case "delete":
+			destroy++
+		}
+	}
+	return
+}
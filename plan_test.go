@@ -0,0 +1,49 @@
+package tfparser
+
+import (
+	"testing"
+)
+
+func TestMapPlan(t *testing.T) {
+	config, err := ParseDir("testdata/plan")
+	if err != nil {
+		t.Fatalf("ParseDir returned an error, %v", err)
+	}
+	plan, err := ParsePlanFile("testdata/plan/plan.json")
+	if err != nil {
+		t.Fatalf("ParsePlanFile returned an error, %v", err)
+	}
+	modules, unmapped := MapPlan(config, plan)
+	if len(modules) != 2 {
+		t.Fatalf("Unexpected number of changed modules %v", len(modules))
+	}
+	for i, expected := range []struct {
+		module    string
+		line      int
+		summary   string
+		resources int
+	}{
+		{"module1", 1, "3 to add, 0 to change, 1 to destroy", 3},
+		{"module2", 5, "0 to add, 1 to change, 0 to destroy", 1},
+	} {
+		mc := modules[i]
+		if mc.Module != expected.module || mc.Pos.Line != expected.line || mc.Summary() != expected.summary || len(mc.Resources) != expected.resources {
+			t.Errorf("Unexpected changes %v at %v: %v, %v resources", mc.Module, mc.Pos, mc.Summary(), len(mc.Resources))
+		}
+	}
+	if len(unmapped) != 1 || unmapped[0].Address != "aws_s3_bucket.logs" {
+		t.Errorf("Unexpected unmapped changes %+v", unmapped)
+	}
+}
+
+func TestParsePlanErrors(t *testing.T) {
+	for _, data := range []string{
+		`{"resource_changes": []}`,
+		`{"format_version": "2.0", "resource_changes": []}`,
+		`{"format_version": "1.2"`,
+	} {
+		if _, err := ParsePlan([]byte(data)); err == nil {
+			t.Errorf("ParsePlan did not return an error for %#q", data)
+		}
+	}
+}
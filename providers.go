@@ -0,0 +1,181 @@
+package tfparser
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProviderIssue describes a provider alias passed into a module that does not match
+// either the provider configurations of the caller or configuration_aliases of the called module
+type ProviderIssue struct {
+	Module  string `json:"module"` // name of the module call
+	Alias   string `json:"alias"`  // provider alias as seen by the called module, e.g. 'aws.alice'
+	Pos     Pos    `json:"pos"`
+	Message string `json:"message"`
+}
+
+func (i ProviderIssue) String() string {
+	return fmt.Sprintf("%v: module %#q: %v", i.Pos, i.Module, i.Message)
+}
+
+// CheckProviders checks provider mappings of all module calls in config against provider
+// configurations and required_providers of config and of called modules.
+// modules maps module call name to the parsed configuration of the called module. Module calls
+// which are not in modules (e.g. registry modules) are checked on the caller side only
+func CheckProviders(config *TFconfig, modules map[string]*TFconfig) []ProviderIssue {
+	var issues []ProviderIssue
+	for _, name := range sortedModuleNames(config) {
+		m := config.Modules[name]
+		aliases := make([]string, 0, len(m.Providers))
+		for alias := range m.Providers {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+
+		// caller side: every aliased provider configuration must be declared.
+		// Default configurations (without alias) do not require provider block
+		for _, alias := range aliases {
+			provName := m.Providers[alias]
+			if !strings.Contains(provName, ".") {
+				continue
+			}
+			if _, exists := config.Providers[provName]; !exists {
+				issues = append(issues, ProviderIssue{name, alias, m.ProvidersPos[alias],
+					fmt.Sprintf("provider configuration %#q passed as %#q is not declared", provName, alias)})
+			}
+		}
+
+		child, exists := modules[name]
+		if !exists {
+			continue
+		}
+		// called module side: every passed alias must be declared in configuration_aliases...
+		for _, alias := range aliases {
+			dot := strings.Index(alias, ".")
+			if dot < 0 {
+				continue
+			}
+			if !child.hasConfigurationAlias(alias[:dot], alias) {
+				issues = append(issues, ProviderIssue{name, alias, m.ProvidersPos[alias],
+					fmt.Sprintf("provider alias %#q is not declared in configuration_aliases of %#q", alias, m.SourcePath)})
+			}
+		}
+		// ...and every declared configuration alias must be passed
+		for _, rp := range child.sortedRequiredProviders() {
+			for _, alias := range rp.ConfigurationAliases {
+				if _, passed := m.Providers[alias]; !passed {
+					issues = append(issues, ProviderIssue{name, alias, rp.Pos,
+						fmt.Sprintf("configuration alias %#q is not passed into the module", alias)})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// CheckProvidersDir parses terraform config in dirname along with all modules it calls
+// using local source paths, and checks provider mappings of all module calls
+func CheckProvidersDir(dirname string) ([]ProviderIssue, error) {
+	config, err := ParseDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	modules, err := ParseModules(config, dirname)
+	if err != nil {
+		return nil, err
+	}
+	return CheckProviders(config, modules), nil
+}
+
+// ParseModules parses all modules called from config using local source paths. Source paths are
+// relative to dirname, the directory config was read from. Result is keyed by module call name
+func ParseModules(config *TFconfig, dirname string) (map[string]*TFconfig, error) {
+	modules := make(map[string]*TFconfig)
+	for name, m := range config.Modules {
+		if !isLocalSource(m.SourcePath) {
+			continue
+		}
+		child, err := ParseDir(filepath.Join(dirname, m.SourcePath))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse module %#q: %v", name, err)
+		}
+		modules[name] = child
+	}
+	return modules, nil
+}
+
+// isLocalSource tells if module source is a local path, rather than registry or remote address
+func isLocalSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+func (c *TFconfig) hasConfigurationAlias(name, alias string) bool {
+	rp, exists := c.RequiredProviders[name]
+	if !exists {
+		return false
+	}
+	for _, a := range rp.ConfigurationAliases {
+		if a == alias {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *TFconfig) sortedRequiredProviders() []*RequiredProvider {
+	names := make([]string, 0, len(c.RequiredProviders))
+	for name := range c.RequiredProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]*RequiredProvider, 0, len(names))
+	for _, name := range names {
+		list = append(list, c.RequiredProviders[name])
+	}
+	return list
+}
+
+func sortedModuleNames(c *TFconfig) []string {
+	names := make([]string, 0, len(c.Modules))
+	for name := range c.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Finding converts issue to a lint finding of ProviderWiringRuleID rule with error severity
+func (i ProviderIssue) Finding() Finding {
+	return Finding{ProviderWiringRuleID, SeverityError, i.Pos, fmt.Sprintf("module %#q: %v", i.Module, i.Message)}
+}
+
+// ProviderWiringRuleID is the ID of the rule returned by NewProviderWiringRule
+const ProviderWiringRuleID = "provider-wiring"
+
+// NewProviderWiringRule returns lint rule, which reports the same issues as CheckProviders.
+// modules are the parsed configurations of called modules, see ParseModules
+func NewProviderWiringRule(modules map[string]*TFconfig) Rule {
+	return providerWiringRule{modules}
+}
+
+type providerWiringRule struct {
+	modules map[string]*TFconfig
+}
+
+func (providerWiringRule) ID() string { return ProviderWiringRuleID }
+
+func (providerWiringRule) Description() string {
+	return "Provider aliases passed into modules must be declared on both sides"
+}
+
+func (providerWiringRule) DefaultSeverity() Severity { return SeverityError }
+
+func (r providerWiringRule) Check(config *TFconfig) []Finding {
+	var findings []Finding
+	for _, issue := range CheckProviders(config, r.modules) {
+		findings = append(findings, issue.Finding())
+	}
+	return findings
+}
@@ -0,0 +1,85 @@
+package tfparser
+
+import (
+	"testing"
+)
+
+func TestParseProviderBlocks(t *testing.T) {
+	config, err := ParseFile("testdata/providers/root/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	if len(config.Providers) != 2 {
+		t.Fatalf("Unexpected number of provider configurations %v, expected 2", len(config.Providers))
+	}
+	p, exists := config.Providers["aws.ap-southeast-2"]
+	if !exists {
+		t.Fatal("provider configuration 'aws.ap-southeast-2' was not found")
+	}
+	if p.Pos.Line != 6 || p.Pos.Column != 1 {
+		t.Fatalf("Unexpected position of provider 'aws.ap-southeast-2': %v, expected 6:1", p.Pos)
+	}
+	m := config.Modules["routing_broken"]
+	if pos := m.ProvidersPos["aws.charlie"]; pos.Line != 29 || pos.Column != 5 {
+		t.Fatalf("Unexpected position of provider alias 'aws.charlie': %v, expected 29:5", pos)
+	}
+}
+
+func TestParseRequiredProviders(t *testing.T) {
+	config, err := ParseFile("testdata/providers/modules/routing/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	rp, exists := config.RequiredProviders["aws"]
+	if !exists {
+		t.Fatal("required provider 'aws' was not found")
+	}
+	if rp.Source != "hashicorp/aws" || rp.Version != ">= 3.0" {
+		t.Fatalf("Unexpected required provider 'aws': source %#q, version %#q", rp.Source, rp.Version)
+	}
+	if len(rp.ConfigurationAliases) != 2 || rp.ConfigurationAliases[1] != "aws.bob" {
+		t.Fatalf("Unexpected configuration aliases %v, expected [aws.alice aws.bob]", rp.ConfigurationAliases)
+	}
+}
+
+func TestParseRequiredProvidersLegacyVersion(t *testing.T) {
+	config, err := ParseString(`
+terraform {
+  required_providers {
+    aws = "~> 3.0"
+    google = { source = "hashicorp/google", version = "4.1.0" }
+  }
+}`)
+	if err != nil {
+		t.Fatalf("ParseString returned an error, %v", err)
+	}
+	if v := config.RequiredProviders["aws"].Version; v != "~> 3.0" {
+		t.Fatalf("Unexpected version of 'aws' required provider %#q", v)
+	}
+	if v := config.RequiredProviders["google"].Version; v != "4.1.0" {
+		t.Fatalf("Unexpected version of 'google' required provider %#q", v)
+	}
+}
+
+func TestCheckProvidersDir(t *testing.T) {
+	issues, err := CheckProvidersDir("testdata/providers/root")
+	if err != nil {
+		t.Fatalf("CheckProvidersDir returned an error, %v", err)
+	}
+	expected := []struct {
+		alias string
+		line  int
+	}{
+		{"aws.alice", 28},   // aws.eu-west-1 is not configured
+		{"aws.charlie", 29}, // not declared by the module
+		{"aws.bob", 5},      // declared by the module, but not passed
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Unexpected number of issues %v, expected %v: %v", len(issues), len(expected), issues)
+	}
+	for i, e := range expected {
+		if issues[i].Module != "routing_broken" || issues[i].Alias != e.alias || issues[i].Pos.Line != e.line {
+			t.Fatalf("Unexpected issue %v, expected alias %#q at line %v", issues[i], e.alias, e.line)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package tfparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Query selects elements of parsed configuration. It is evaluated against the same document
+WriteJSON produces, so field names are the ones of json struct tags. For convenience top level
+collections can be named in singular: 'module', 'provider', 'required_provider', 'object', 'import',
+'check' and 'block'.
+
+A query is a sequence of steps:
+
+	name              field of an object, e.g. 'module' or 'source'
+	["key"]           element of a collection with given key, e.g. 'module["module1"]'
+	*  or  [*]        all elements of a collection
+	[path]            elements of a collection which have path, e.g. 'module[providers["aws.bob"]]'
+	[path op "value"] elements of a collection where path is compared to value with op:
+	                  '=' equal, '!=' not equal, '~=' matches regular expression
+
+where path is a sequence of names and ["key"] steps, relative to an element. Steps are separated
+with dots, except for bracketed ones. Examples:
+
+	module[source~="vpc-routing"].providers["aws.bob"]
+	module[providers["aws.bob"]="aws.ap-southeast-2"].source
+	module.*.pos.line
+*/
+type Query struct {
+	text  string
+	steps []queryStep
+}
+
+// QueryResult is an element selected by query
+type QueryResult struct {
+	Path  string      `json:"path"`  // path to the element, e.g. 'module["module1"].source'
+	Value interface{} `json:"value"` // string, json.Number, bool, []interface{} or map[string]interface{}
+}
+
+type queryStepKind int
+
+const (
+	stepField queryStepKind = iota
+	stepKey
+	stepAll
+	stepFilter
+)
+
+type queryStep struct {
+	kind  queryStepKind
+	name  string      // field name or key
+	path  []queryStep // stepFilter: path relative to element, only stepField and stepKey
+	op    string      // stepFilter: empty if filter only checks path exists
+	value string      // stepFilter: value to compare with
+	re    *regexp.Regexp
+}
+
+var querySingular = map[string]string{
+	"module":            "modules",
+	"provider":          "providers",
+	"required_provider": "required_providers",
+	"object":            "objects",
+	"import":            "imports",
+	"check":             "checks",
+	"block":             "blocks",
+}
+
+// ParseQuery parses query q, see Query for the syntax
+func ParseQuery(q string) (*Query, error) {
+	qp := &queryParser{data: q}
+	steps, err := qp.parseSteps(false)
+	if err != nil {
+		return nil, err
+	}
+	if qp.i < len(qp.data) {
+		return nil, qp.errorf("unexpected %#q", qp.data[qp.i:])
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("Empty query")
+	}
+	if steps[0].kind == stepField && querySingular[steps[0].name] != "" {
+		steps[0].name = querySingular[steps[0].name]
+	}
+	return &Query{q, steps}, nil
+}
+
+// Select parses query q and evaluates it against config
+func Select(config *TFconfig, q string) ([]QueryResult, error) {
+	query, err := ParseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	return query.Eval(config)
+}
+
+func (q *Query) String() string {
+	return q.text
+}
+
+// Eval evaluates query against config. Results are ordered by their keys within collections
+func (q *Query) Eval(config *TFconfig) ([]QueryResult, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	results := []QueryResult{{"", doc}}
+	for _, step := range q.steps {
+		var next []QueryResult
+		for _, r := range results {
+			next = append(next, step.apply(r)...)
+		}
+		results = next
+	}
+	return results, nil
+}
+
+func (s queryStep) apply(r QueryResult) []QueryResult {
+	switch s.kind {
+	case stepField, stepKey:
+		m, ok := r.Value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v, exists := m[s.name]
+		if !exists {
+			return nil
+		}
+		return []QueryResult{{r.Path + s.pathSuffix(r.Path), v}}
+	}
+	// stepAll and stepFilter
+	var results []QueryResult
+	switch v := r.Value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			results = append(results, QueryResult{r.Path + fmt.Sprintf("[%v]", strconv.Quote(k)), v[k]})
+		}
+	case []interface{}:
+		for i, item := range v {
+			results = append(results, QueryResult{r.Path + fmt.Sprintf("[%d]", i), item})
+		}
+	}
+	if s.kind == stepAll {
+		return results
+	}
+	filtered := results[:0]
+	for _, r := range results {
+		if s.matches(r.Value) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+func (s queryStep) pathSuffix(path string) string {
+	if s.kind == stepKey {
+		return fmt.Sprintf("[%v]", strconv.Quote(s.name))
+	}
+	if path == "" {
+		return s.name
+	}
+	return "." + s.name
+}
+
+// matches tells if value satisfies filter step s
+func (s queryStep) matches(value interface{}) bool {
+	results := []QueryResult{{"", value}}
+	for _, step := range s.path {
+		var next []QueryResult
+		for _, r := range results {
+			next = append(next, step.apply(r)...)
+		}
+		results = next
+	}
+	if s.op == "" {
+		return len(results) > 0
+	}
+	for _, r := range results {
+		var v string
+		switch rv := r.Value.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		default:
+			v = fmt.Sprint(rv)
+		}
+		switch s.op {
+		case "=":
+			return v == s.value
+		case "!=":
+			return v != s.value
+		case "~=":
+			return s.re.MatchString(v)
+		}
+	}
+	// path does not exist: nothing is equal to it, but everything is not equal
+	return s.op == "!="
+}
+
+type queryParser struct {
+	data string
+	i    int
+}
+
+func (qp *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Invalid query %#q at position %d: %v", qp.data, qp.i+1, fmt.Sprintf(format, args...))
+}
+
+func (qp *queryParser) skipSpaces() {
+	for qp.i < len(qp.data) && (qp.data[qp.i] == ' ' || qp.data[qp.i] == '\t') {
+		qp.i++
+	}
+}
+
+func (qp *queryParser) peekByte() byte {
+	qp.skipSpaces()
+	if qp.i >= len(qp.data) {
+		return 0
+	}
+	return qp.data[qp.i]
+}
+
+// parseSteps parses steps till the end of query or, in relative path of a filter, till
+// comparison operator or closing bracket. Only names and keys are allowed in relative path
+func (qp *queryParser) parseSteps(relative bool) ([]queryStep, error) {
+	var steps []queryStep
+	for {
+		c := qp.peekByte()
+		switch {
+		case c == 0, relative && (c == ']' || c == '=' || c == '!' || c == '~'):
+			return steps, nil
+		case c == '.' && len(steps) > 0:
+			qp.i++
+			if qp.peekByte() == '*' && !relative {
+				qp.i++
+				steps = append(steps, queryStep{kind: stepAll})
+				continue
+			}
+			name := qp.parseName()
+			if name == "" {
+				return nil, qp.errorf("name expected after '.'")
+			}
+			steps = append(steps, queryStep{kind: stepField, name: name})
+		case c == '*' && len(steps) == 0:
+			return nil, qp.errorf("query can not start with '*'")
+		case c == '[':
+			qp.i++
+			step, err := qp.parseBracket(relative)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+		case len(steps) == 0:
+			name := qp.parseName()
+			if name == "" {
+				return nil, qp.errorf("name expected")
+			}
+			steps = append(steps, queryStep{kind: stepField, name: name})
+		default:
+			return nil, qp.errorf("unexpected %#q", string(c))
+		}
+	}
+}
+
+// parseBracket parses step in brackets, after the opening one
+func (qp *queryParser) parseBracket(relative bool) (queryStep, error) {
+	var step queryStep
+	switch c := qp.peekByte(); {
+	case c == '"':
+		key, err := qp.parseString()
+		if err != nil {
+			return step, err
+		}
+		step = queryStep{kind: stepKey, name: key}
+	case c == '*' && !relative:
+		qp.i++
+		step = queryStep{kind: stepAll}
+	case !relative:
+		path, err := qp.parseSteps(true)
+		if err != nil {
+			return step, err
+		}
+		if len(path) == 0 {
+			return step, qp.errorf("path expected in filter")
+		}
+		step = queryStep{kind: stepFilter, path: path}
+		for _, op := range []string{"!=", "~=", "="} {
+			if strings.HasPrefix(qp.data[qp.i:], op) {
+				step.op = op
+				qp.i += len(op)
+				break
+			}
+		}
+		if step.op != "" {
+			qp.skipSpaces()
+			step.value, err = qp.parseString()
+			if err != nil {
+				return step, err
+			}
+		}
+		if step.op == "~=" {
+			step.re, err = regexp.Compile(step.value)
+			if err != nil {
+				return step, qp.errorf("invalid regular expression: %v", err)
+			}
+		}
+	default:
+		return step, qp.errorf("key expected")
+	}
+	if qp.peekByte() != ']' {
+		return step, qp.errorf("']' expected")
+	}
+	qp.i++
+	return step, nil
+}
+
+func (qp *queryParser) parseName() string {
+	qp.skipSpaces()
+	start := qp.i
+	for qp.i < len(qp.data) && isIdentifierChar(qp.data[qp.i]) {
+		qp.i++
+	}
+	return qp.data[start:qp.i]
+}
+
+func (qp *queryParser) parseString() (string, error) {
+	if qp.i >= len(qp.data) || qp.data[qp.i] != '"' {
+		return "", qp.errorf("string expected")
+	}
+	end := scanString(qp.data, qp.i)
+	s, err := strconv.Unquote(qp.data[qp.i:end])
+	if err != nil {
+		return "", qp.errorf("invalid string %v", qp.data[qp.i:end])
+	}
+	qp.i = end
+	return s, nil
+}
@@ -0,0 +1,63 @@
+package tfparser
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	config, err := ParseFile("testdata/tf/main.tf")
+	if err != nil {
+		t.Fatalf("ParseFile returned an error, %v", err)
+	}
+	tests := []struct {
+		query    string
+		expected [][2]string // path and value
+	}{
+		{`module[source~="vpc-routing"].providers["aws.bob"]`,
+			[][2]string{{`modules["module2"].providers["aws.bob"]`, "aws.ap-southeast-2"}}},
+		{`module[providers["aws.bob"]].source`,
+			[][2]string{{`modules["module1"].source`, "../../modules/module_name"}, {`modules["module2"].source`, "../../modules/vpc-routing"}}},
+		{`module[parameters.bob_vpc_name != "Development VPC"][*].pos.line`,
+			[][2]string{}},
+		{`module[parameters.bob_vpc_name != "Development VPC"].pos.line`,
+			[][2]string{{`modules["module2"].pos.line`, "29"}}},
+		{`module.*.parameters.numeric_value`,
+			[][2]string{{`modules["module1"].parameters.numeric_value`, "12"}}},
+		{`modules["module1"].providers[*]`,
+			[][2]string{{`modules["module1"].providers["aws.alice"]`, "aws.us-east-1"}, {`modules["module1"].providers["aws.bob"]`, "aws.ap-southeast-2"}}},
+		{`module["nope"].source`, [][2]string{}},
+	}
+	for _, test := range tests {
+		results, err := Select(config, test.query)
+		if err != nil {
+			t.Fatalf("Select(%#q) returned an error, %v", test.query, err)
+		}
+		if len(results) != len(test.expected) {
+			t.Fatalf("Select(%#q) returned %v results, expected %v: %v", test.query, len(results), len(test.expected), results)
+		}
+		for i, r := range results {
+			if r.Path != test.expected[i][0] || fmt.Sprint(r.Value) != test.expected[i][1] {
+				t.Fatalf("Select(%#q) returned unexpected result %v = %v, expected %v = %v", test.query, r.Path, r.Value, test.expected[i][0], test.expected[i][1])
+			}
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	queries := []string{
+		``,
+		`*`,
+		`module[`,
+		`module[source=]`,
+		`module[source~="("]`,
+		`module.`,
+		`module]`,
+		`module["x"`,
+	}
+	for _, q := range queries {
+		if _, err := ParseQuery(q); err == nil {
+			t.Fatalf("ParseQuery(%#q) did not return an error", q)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package tfparser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RenameModule renames module call from to the name to in files of a configuration: the label of
+// the module block, every 'module.from' reference, including the ones in string templates, and
+// appends 'moved' block to the file declaring the module, so terraform moves existing state
+func RenameModule(files []*CSTFile, from, to string) error {
+	if !isCSTIdentifier(to) {
+		return fmt.Errorf("Invalid module name %#q", to)
+	}
+	var declaring *CSTFile
+	var block *CSTNode
+	for _, f := range files {
+		if f.Body.Block("module", to) != nil {
+			return fmt.Errorf("Module %#q is already declared in %v", to, f.Filename)
+		}
+		if b := f.Body.Block("module", from); b != nil {
+			declaring, block = f, b
+		}
+	}
+	if declaring == nil {
+		return fmt.Errorf("Module %#q is not found", from)
+	}
+
+	for _, c := range block.Children[1:] {
+		if isTokenKind(c, TokenString) {
+			c.Token.Text = strconv.Quote(to)
+			break
+		}
+	}
+	for _, f := range files {
+		renameModuleReferences(f.Body.Tokens(), from, to)
+	}
+
+	moved, err := ParseCST([]byte(fmt.Sprintf("moved {\n  from = module.%v\n  to   = module.%v\n}\n", from, to)), "")
+	if err != nil {
+		return err
+	}
+	body := declaring.Body
+	if n := len(body.Children); n > 0 && !isTokenKind(body.Children[n-1], TokenNewline) {
+		body.Children = append(body.Children, newlineNode())
+	}
+	if len(body.Children) > 0 {
+		body.Children = append(body.Children, newlineNode())
+	}
+	body.Children = append(body.Children, moved.Body.Children...)
+	return nil
+}
+
+// RenameModuleDir renames module call from to the name to in *.tf files of dirname, see
+// RenameModule. Only changed files are written
+func RenameModuleDir(dirname, from, to string) error {
+	dirList, err := ioutil.ReadDir(dirname)
+	if err != nil {
+		return err
+	}
+	var files []*CSTFile
+	var sources [][]byte
+	for _, fi := range dirList {
+		if !strings.HasSuffix(fi.Name(), ".tf") {
+			continue
+		}
+		filename := filepath.Join(dirname, fi.Name())
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		f, err := ParseCST(data, filename)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		sources = append(sources, data)
+	}
+	if err := RenameModule(files, from, to); err != nil {
+		return err
+	}
+	for i, f := range files {
+		data := f.Bytes()
+		if string(data) == string(sources[i]) {
+			continue
+		}
+		info, err := os.Stat(f.Filename)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(f.Filename, data, info.Mode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameModuleReferences replaces 'module.from' traversals in tokens with 'module.to'
+func renameModuleReferences(tokens []*Token, from, to string) {
+	for i, t := range tokens {
+		switch t.Kind {
+		case TokenString, TokenHeredoc:
+			t.Text = renameModuleInTemplates(t.Text, from, to)
+		case TokenIdentifier:
+			if t.Text == "module" && i+2 < len(tokens) && tokens[i+1].Text == "." && tokens[i+2].Text == from &&
+				(i == 0 || tokens[i-1].Text != ".") {
+				tokens[i+2].Text = to
+			}
+		}
+	}
+}
+
+// renameModuleInTemplates replaces 'module.from' traversals in template sequences of string or heredoc s
+func renameModuleInTemplates(s, from, to string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${") || strings.HasPrefix(s[i:], "%%{"):
+			b.WriteString(s[i : i+3])
+			i += 3
+		case strings.HasPrefix(s[i:], "${") || strings.HasPrefix(s[i:], "%{"):
+			end := scanTemplate(s, i+1)
+			if end-1 < i+2 || s[end-1] != '}' {
+				// unterminated template sequence
+				b.WriteString(s[i:])
+				i = len(s)
+				continue
+			}
+			inner := s[i+2 : end-1]
+			if tokens, err := newParser(inner, "", &TFconfig{}).popTokens(); err == nil {
+				renameModuleReferences(tokens, from, to)
+				var ib strings.Builder
+				for _, t := range tokens {
+					ib.WriteString(t.Text)
+				}
+				inner = ib.String()
+			}
+			b.WriteString(s[i:i+2] + inner + s[end-1:end])
+			i = end
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String()
+}
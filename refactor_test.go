@@ -0,0 +1,95 @@
+package tfparser
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var renameTestFiles = map[string]string{
+	"main.tf": `module "module1" {
+  source = "../../modules/vpc"
+}
+
+module "module10" {
+  source = "../../modules/vpc"
+  vpc_id = module.module1.vpc_id
+}`,
+	"outputs.tf": `output "vpc" {
+  value = "vpc ${module.module1.vpc_id} of module.module1 and ${module.module10.vpc_id}"
+}
+
+output "others" {
+  # module.module1 in comment is not changed
+  value      = [module.module10.vpc_id, data.x.module.module1]
+  depends_on = [module.module1]
+}
+`,
+	"README.md": "module.module1\n",
+}
+
+func TestRenameModuleDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range renameTestFiles {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RenameModuleDir(dir, "module1", "vpc"); err != nil {
+		t.Fatalf("RenameModuleDir returned an error, %v", err)
+	}
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	main := read("main.tf")
+	expected := strings.Replace(renameTestFiles["main.tf"], `"module1"`, `"vpc"`, 1)
+	expected = strings.Replace(expected, "module.module1.vpc_id", "module.vpc.vpc_id", 1)
+	expected += "\n\nmoved {\n  from = module.module1\n  to   = module.vpc\n}\n"
+	if main != expected {
+		t.Errorf("Unexpected main.tf:\n%v\nexpected:\n%v", main, expected)
+	}
+	outputs := read("outputs.tf")
+	expected = strings.NewReplacer(
+		"${module.module1.vpc_id}", "${module.vpc.vpc_id}",
+		"[module.module1]", "[module.vpc]",
+	).Replace(renameTestFiles["outputs.tf"])
+	if outputs != expected {
+		t.Errorf("Unexpected outputs.tf:\n%v\nexpected:\n%v", outputs, expected)
+	}
+	if readme := read("README.md"); readme != renameTestFiles["README.md"] {
+		t.Errorf("README.md was changed")
+	}
+
+	config, err := ParseDir(dir)
+	if err != nil {
+		t.Fatalf("Unable to parse renamed configuration, %v", err)
+	}
+	if config.Modules["vpc"] == nil || config.Modules["module1"] != nil {
+		t.Errorf("Module was not renamed: %v", config.Modules)
+	}
+}
+
+func TestRenameModuleErrors(t *testing.T) {
+	f, err := ParseCST([]byte(renameTestFiles["main.tf"]), "main.tf")
+	if err != nil {
+		t.Fatalf("ParseCST returned an error, %v", err)
+	}
+	for _, names := range [][2]string{
+		{"unknown", "vpc"},
+		{"module1", "module10"},
+		{"module1", "invalid name"},
+	} {
+		if err := RenameModule([]*CSTFile{f}, names[0], names[1]); err == nil {
+			t.Errorf("RenameModule(%v, %v) did not return an error", names[0], names[1])
+		}
+	}
+	if string(f.Bytes()) != renameTestFiles["main.tf"] {
+		t.Errorf("Failed rename changed the file:\n%s", f.Bytes())
+	}
+}
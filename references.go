@@ -0,0 +1,213 @@
+package tfparser
+
+import (
+	"strings"
+)
+
+// findReferences returns all traversals found in expression or block body expr, which may refer
+// to other objects, e.g. 'module.vpc.vpc_id' or 'aws_vpc.main.id'. Strings are only searched
+// inside of template sequences, comments are ignored. Iterators of dynamic blocks, e.g. 'ingress.value',
+// are not references inside of 'content' blocks
+func findReferences(expr string) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	findReferencesInRange(expr, 0, len(expr), nil, add)
+	return refs
+}
+
+// findReferencesInRange looks for references in data[i:end], traversals starting with a name in
+// exclude, e.g. iterators of enclosing dynamic blocks, are not references
+func findReferencesInRange(data string, i, end int, exclude map[string]bool, add func(string)) {
+	for i < end {
+		c := data[i]
+		switch {
+		case c == '#' || strings.HasPrefix(data[i:end], "//"):
+			eol := strings.IndexByte(data[i:end], '\n')
+			if eol < 0 {
+				return
+			}
+			i += eol + 1
+		case strings.HasPrefix(data[i:end], "/*"):
+			closing := strings.Index(data[i+2:end], "*/")
+			if closing < 0 {
+				return
+			}
+			i += closing + 4
+		case c == '"':
+			strEnd := scanString(data, i)
+			findTemplateReferences(data, i+1, strEnd, exclude, add)
+			i = strEnd
+		case strings.HasPrefix(data[i:end], "<<"):
+			docEnd := scanHeredoc(data, i)
+			findTemplateReferences(data, i+2, docEnd, exclude, add)
+			i = docEnd
+		case isIdentifierStart(c) && (i == 0 || !isIdentifierChar(data[i-1]) && data[i-1] != '.'):
+			j := i
+			for j < end && isIdentifierChar(data[j]) {
+				j++
+			}
+			// attribute access like '.vpc_id', but not splat '.*' or index '[0]'
+			for j+1 < end && data[j] == '.' && isIdentifierStart(data[j+1]) {
+				j++
+				for j < end && isIdentifierChar(data[j]) {
+					j++
+				}
+			}
+			// attribute names and object keys are not references, neither are single identifiers
+			// like keywords, function names or nested block types
+			k := skipSpaces(data, j, end)
+			if data[i:j] == "dynamic" {
+				if blockEnd := findDynamicReferences(data, k, end, exclude, add); blockEnd > 0 {
+					i = blockEnd
+					continue
+				}
+			}
+			dot := strings.IndexByte(data[i:j], '.')
+			if dot > 0 && !exclude[data[i:i+dot]] && (k >= end || data[k] != '=' || k+1 < end && data[k+1] == '=') {
+				add(data[i:j])
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+}
+
+// findTemplateReferences looks for references in '${...}' and '%{...}' sequences of string data[i:end]
+func findTemplateReferences(data string, i, end int, exclude map[string]bool, add func(string)) {
+	for ; i < end; i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '$', '%':
+			if i+1 < end && data[i+1] == '{' {
+				tmplEnd := scanTemplate(data, i+1)
+				findReferencesInRange(data, i+2, tmplEnd-1, exclude, add)
+				i = tmplEnd - 1
+			}
+		}
+	}
+}
+
+// findDynamicReferences looks for references in dynamic block, which label starts at data[i], and
+// returns index right after the block, or 0 if data[i:end] is not a labeled block. Iterator of the
+// block, its label unless 'iterator' attribute is set, is excluded inside of its 'content' block
+func findDynamicReferences(data string, i, end int, exclude map[string]bool, add func(string)) int {
+	if i >= end || data[i] != '"' {
+		return 0
+	}
+	labelEnd := scanString(data, i)
+	iterator := data[i+1 : labelEnd-1]
+	open := skipSpaces(data, labelEnd, end)
+	if open >= end || data[open] != '{' {
+		return 0
+	}
+	blockEnd := scanBlockEnd(data, open, end)
+	bodyEnd := blockEnd - 1
+
+	// attributes and blocks of the body itself, at depth 0
+	contentStart, contentEnd := -1, -1
+	for k := open + 1; k < bodyEnd; {
+		switch c := data[k]; {
+		case c == '"':
+			k = scanString(data, k)
+		case c == '#' || strings.HasPrefix(data[k:bodyEnd], "//") || strings.HasPrefix(data[k:bodyEnd], "/*"):
+			k = skipCommentAt(data, k, bodyEnd)
+		case c == '<' && strings.HasPrefix(data[k:bodyEnd], "<<"):
+			k = scanHeredoc(data, k)
+		case c == '{' || c == '[' || c == '(':
+			k = scanBlockEnd(data, k, bodyEnd)
+		case isIdentifierStart(c) && !isIdentifierChar(data[k-1]) && data[k-1] != '.':
+			j := k
+			for j < bodyEnd && isIdentifierChar(data[j]) {
+				j++
+			}
+			name := data[k:j]
+			next := skipSpaces(data, j, bodyEnd)
+			switch {
+			case name == "iterator" && next < bodyEnd && data[next] == '=':
+				v := skipSpaces(data, next+1, bodyEnd)
+				w := v
+				for w < bodyEnd && isIdentifierChar(data[w]) {
+					w++
+				}
+				iterator = data[v:w]
+			case name == "content" && next < bodyEnd && data[next] == '{':
+				contentStart, contentEnd = next, scanBlockEnd(data, next, bodyEnd)
+			}
+			k = j
+		default:
+			k++
+		}
+	}
+	if contentStart < 0 {
+		findReferencesInRange(data, open+1, bodyEnd, exclude, add)
+		return blockEnd
+	}
+	inner := map[string]bool{iterator: true}
+	for name := range exclude {
+		inner[name] = true
+	}
+	findReferencesInRange(data, open+1, contentStart, exclude, add)
+	findReferencesInRange(data, contentStart, contentEnd, inner, add)
+	findReferencesInRange(data, contentEnd, bodyEnd, exclude, add)
+	return blockEnd
+}
+
+// scanBlockEnd returns index right after the bracket closing the one at data[i], brackets in
+// strings, heredocs and comments are not counted
+func scanBlockEnd(data string, i, end int) int {
+	depth := 0
+	for i < end {
+		switch c := data[i]; {
+		case c == '"':
+			i = scanString(data, i)
+			continue
+		case c == '<' && strings.HasPrefix(data[i:end], "<<"):
+			i = scanHeredoc(data, i)
+			continue
+		case c == '#' || strings.HasPrefix(data[i:end], "//") || strings.HasPrefix(data[i:end], "/*"):
+			i = skipCommentAt(data, i, end)
+			continue
+		case c == '{' || c == '[' || c == '(':
+			depth++
+		case c == '}' || c == ']' || c == ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+	return end
+}
+
+// skipCommentAt returns index right after the comment started at data[i]
+func skipCommentAt(data string, i, end int) int {
+	if strings.HasPrefix(data[i:end], "/*") {
+		closing := strings.Index(data[i+2:end], "*/")
+		if closing < 0 {
+			return end
+		}
+		return i + closing + 4
+	}
+	eol := strings.IndexByte(data[i:end], '\n')
+	if eol < 0 {
+		return end
+	}
+	return i + eol + 1
+}
+
+// skipSpaces returns index of the first character of data[i:end] which is not a space or a tab
+func skipSpaces(data string, i, end int) int {
+	for i < end && (data[i] == ' ' || data[i] == '\t') {
+		i++
+	}
+	return i
+}
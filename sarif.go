@@ -0,0 +1,200 @@
+package tfparser
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// SARIF 2.1.0 log, only the parts WriteSARIF uses
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           sarifRegion      `json:"region"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"` // column right after the region
+}
+
+// sarifRootID is the base id of URIs relative to the scanned root
+const sarifRootID = "%SRCROOT%"
+
+var sarifLevels = map[Severity]string{
+	SeverityInfo:    "note",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+// WriteSARIF writes findings as SARIF 2.1.0 log to w. rules describe all rules findings may
+// come from, e.g. Linter.Rules(), severities of findings take precedence over rules' default ones.
+// File URIs of findings are relative to root, the directory which was scanned. sources are contents
+// of files findings refer to, keyed by file name, regions of findings in them end with the attribute
+// or block the finding starts at; sources may be nil, then regions have start position only
+func WriteSARIF(w io.Writer, root string, rules []Rule, findings []Finding, sources map[string][]byte) error {
+	run := sarifRun{
+		Tool: sarifTool{sarifDriver{
+			Name:           "tfparser",
+			InformationURI: "https://github.com/kabenin/tfparser",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLoc{
+			sarifRootID: {URI: fileURI(abs) + "/"},
+		}
+	}
+	ruleIndex := make(map[string]int)
+	for _, r := range rules {
+		ruleIndex[r.ID()] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   r.ID(),
+			ShortDescription:     sarifMessage{r.Description()},
+			DefaultConfiguration: sarifConfiguration{sarifLevels[r.DefaultSeverity()]},
+		})
+	}
+	for _, f := range findings {
+		region := sarifRegion{StartLine: f.Pos.Line, StartColumn: f.Pos.Column}
+		if end, found := regionEnd(string(sources[f.Pos.Filename]), f.Pos); found {
+			region.EndLine, region.EndColumn = end.Line, end.Column
+		}
+		index, exists := ruleIndex[f.RuleID]
+		if !exists {
+			index = -1
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    f.RuleID,
+			RuleIndex: index,
+			Level:     sarifLevels[f.Severity],
+			Message:   sarifMessage{f.Message},
+			Locations: []sarifLocation{{sarifPhysicalLocation{
+				ArtifactLocation: artifactLocation(root, f.Pos.Filename),
+				Region:           region,
+			}}},
+		})
+	}
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// regionEnd returns position right after the attribute or block, which starts at pos in data.
+// Nothing is found if there is neither at pos
+func regionEnd(data string, pos Pos) (Pos, bool) {
+	offset := 0
+	for line := 1; line < pos.Line; line++ {
+		eol := strings.IndexByte(data[offset:], '\n')
+		if eol < 0 {
+			return Pos{}, false
+		}
+		offset += eol + 1
+	}
+	offset += pos.Column - 1
+	if pos.Line < 1 || pos.Column < 1 || offset >= len(data) {
+		return Pos{}, false
+	}
+	p := newParser(data, pos.Filename, &TFconfig{})
+	p.i = offset
+	if tok := p.pop(); tok == "" || strings.Contains(symbols, tok) {
+		return Pos{}, false
+	}
+	if p.peek() == "=" {
+		p.pop()
+		start := p.i
+		p.i = start + len(p.popExpression())
+		return p.pos(), true
+	}
+	for tok := p.peek(); tok != "{"; tok = p.peek() {
+		if tok == "" || strings.Contains(symbols, tok) {
+			return Pos{}, false
+		}
+		p.pop()
+	}
+	p.i = scanBlockEnd(data, p.i, len(data))
+	return p.pos(), true
+}
+
+// artifactLocation returns location of filename relative to root, or absolute file URI
+// if filename is outside of root
+func artifactLocation(root, filename string) sarifArtifactLoc {
+	rel, err := filepath.Rel(root, filename)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return sarifArtifactLoc{URI: (&url.URL{Path: filepath.ToSlash(rel)}).String(), URIBaseID: sarifRootID}
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		return sarifArtifactLoc{URI: fileURI(abs)}
+	}
+	return sarifArtifactLoc{URI: (&url.URL{Path: filepath.ToSlash(filename)}).String()}
+}
+
+func fileURI(abs string) string {
+	path := filepath.ToSlash(abs)
+	if !strings.HasPrefix(path, "/") {
+		// windows drive letter
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,77 @@
+package tfparser
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	config, err := ParseDir("testdata/lint")
+	if err != nil {
+		t.Fatalf("ParseDir returned an error, %v", err)
+	}
+	source, err := ioutil.ReadFile("testdata/lint/main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLinter()
+	var b strings.Builder
+	sources := map[string][]byte{"testdata/lint/main.tf": source}
+	if err := WriteSARIF(&b, "testdata/lint", l.Rules(), l.Lint(config), sources); err != nil {
+		t.Fatalf("WriteSARIF returned an error, %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal([]byte(b.String()), &log); err != nil {
+		t.Fatalf("WriteSARIF produced invalid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected SARIF version %#q or number of runs %v", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(DefaultRules) {
+		t.Fatalf("Unexpected number of rules %v, expected %v", len(run.Tool.Driver.Rules), len(DefaultRules))
+	}
+	if len(run.Results) != 8 {
+		t.Fatalf("Unexpected number of results %v, expected 8", len(run.Results))
+	}
+	r := run.Results[0]
+	if r.RuleID != "unused-provider" || r.Level != "warning" || run.Tool.Driver.Rules[r.RuleIndex].ID != r.RuleID {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+	loc := r.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "main.tf" || loc.ArtifactLocation.URIBaseID != sarifRootID {
+		t.Fatalf("Unexpected artifact location %+v, expected 'main.tf' relative to %v", loc.ArtifactLocation, sarifRootID)
+	}
+	if loc.Region.StartLine != 5 || loc.Region.StartColumn != 1 || loc.Region.EndLine != 8 || loc.Region.EndColumn != 2 {
+		t.Fatalf("Unexpected region %+v, expected 5:1-8:2", loc.Region)
+	}
+	// region of attribute ends with its value
+	region := run.Results[2].Locations[0].PhysicalLocation.Region
+	if run.Results[2].RuleID != "hardcoded-region" || region.StartLine != 25 || region.EndLine != 25 || region.EndColumn != 24 {
+		t.Fatalf("Unexpected region %+v of result %v, expected 25:3-25:24", region, run.Results[2].RuleID)
+	}
+	// without sources regions have start position only
+	b.Reset()
+	if err := WriteSARIF(&b, "testdata/lint", l.Rules(), l.Lint(config), nil); err != nil {
+		t.Fatalf("WriteSARIF returned an error, %v", err)
+	}
+	var plain sarifLog
+	if err := json.Unmarshal([]byte(b.String()), &plain); err != nil {
+		t.Fatalf("WriteSARIF produced invalid JSON: %v", err)
+	}
+	if region := plain.Runs[0].Results[0].Locations[0].PhysicalLocation.Region; region.StartLine != 5 || region.EndLine != 0 {
+		t.Fatalf("Unexpected region %+v without sources, expected 5:1", region)
+	}
+	if !strings.HasSuffix(run.OriginalURIBaseIDs[sarifRootID].URI, "/testdata/lint/") {
+		t.Fatalf("Unexpected root URI %#q", run.OriginalURIBaseIDs[sarifRootID].URI)
+	}
+}
+
+func TestSARIFArtifactLocationOutsideRoot(t *testing.T) {
+	loc := artifactLocation("testdata/lint", "testdata/tf/main.tf")
+	if loc.URIBaseID != "" || !strings.HasPrefix(loc.URI, "file:///") || !strings.HasSuffix(loc.URI, "/testdata/tf/main.tf") {
+		t.Fatalf("Unexpected artifact location for a file outside of root: %+v", loc)
+	}
+}
@@ -0,0 +1,152 @@
+package tfparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// StateFile is the name of local state file
+const StateFile = "terraform.tfstate"
+
+// State is terraform state of format version 4, as written by terraform 0.12 and newer
+type State struct {
+	Version          int              `json:"version"`
+	TerraformVersion string           `json:"terraform_version"`
+	Serial           int              `json:"serial"`
+	Lineage          string           `json:"lineage"`
+	Resources        []*StateResource `json:"resources"`
+}
+
+// StateResource is a resource or data source recorded in state
+type StateResource struct {
+	Module    string           `json:"module,omitempty"` // module instance address, e.g. 'module.vpc["a"]', empty for root module
+	Mode      string           `json:"mode"`             // 'managed' or 'data'
+	Type      string           `json:"type"`
+	Name      string           `json:"name"`
+	Provider  string           `json:"provider"` // e.g. 'provider["registry.terraform.io/hashicorp/aws"].west'
+	Instances []*StateInstance `json:"instances"`
+}
+
+// StateInstance is an instance of resource, one for each count or for_each key
+type StateInstance struct {
+	IndexKey   interface{}            `json:"index_key,omitempty"` // number, string or nil for single instance
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// Address returns resource address without module, e.g. 'aws_vpc.this' or 'data.aws_ami.ubuntu'
+func (r *StateResource) Address() string {
+	if r.Mode == "data" {
+		return "data." + r.Type + "." + r.Name
+	}
+	return r.Type + "." + r.Name
+}
+
+// ParseState parses local state data. Only state format version 4 is supported
+func ParseState(data []byte) (*State, error) {
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("Unable to parse state: %v", err)
+	}
+	if state.Version != 4 {
+		return nil, fmt.Errorf("Unsupported state version %v, only version 4 is supported", state.Version)
+	}
+	return &state, nil
+}
+
+// ParseStateFile reads filename and parses it with ParseState
+func ParseStateFile(filename string) (*State, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	state, err := ParseState(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	return state, nil
+}
+
+// Modules groups resources of state by module instance address, empty for root module
+func (s *State) Modules() map[string][]*StateResource {
+	modules := make(map[string][]*StateResource)
+	for _, r := range s.Resources {
+		modules[r.Module] = append(modules[r.Module], r)
+	}
+	return modules
+}
+
+// StateModule is a module instance recorded in state
+type StateModule struct {
+	Address   string           `json:"address"`        // e.g. 'module.vpc["a"]' or 'module.vpc.module.subnets'
+	Call      string           `json:"call,omitempty"` // name of module call of root module, empty if orphaned
+	Resources []*StateResource `json:"resources"`
+}
+
+// StateReport is the result of CorrelateState
+type StateReport struct {
+	Modules    []*StateModule `json:"modules,omitempty"`     // module instances linked to module calls, sorted by address
+	NotApplied []string       `json:"not_applied,omitempty"` // module calls without any resources in state, sorted
+	Orphaned   []*StateModule `json:"orphaned,omitempty"`    // module instances without module call, sorted by address
+}
+
+// CorrelateState links module instances of state with module calls of root module config.
+// Nested module instances, e.g. 'module.a.module.b', belong to the call of their top level module.
+// Instances of a call renamed with 'moved' block are linked to the new call, instead of being orphaned
+func CorrelateState(config *TFconfig, state *State) *StateReport {
+	moved := make(map[string]string)
+	for _, m := range config.Moved {
+		from, fromIndex, fromRest := splitModuleAddress(m.From)
+		to, toIndex, toRest := splitModuleAddress(m.To)
+		if from != "" && to != "" && fromIndex == "" && toIndex == "" && fromRest == "" && toRest == "" {
+			moved[from] = to
+		}
+	}
+
+	report := &StateReport{}
+	applied := make(map[string]bool)
+	modules := state.Modules()
+	addresses := make([]string, 0, len(modules))
+	for address := range modules {
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		sm := &StateModule{Address: address, Resources: modules[address]}
+		call, _, _ := splitModuleAddress(address)
+		if to, exists := moved[call]; exists {
+			call = to
+		}
+		if _, exists := config.Modules[call]; !exists {
+			report.Orphaned = append(report.Orphaned, sm)
+			continue
+		}
+		sm.Call = call
+		applied[call] = true
+		report.Modules = append(report.Modules, sm)
+	}
+	for _, name := range sortedModuleNames(config) {
+		if !applied[name] {
+			report.NotApplied = append(report.NotApplied, name)
+		}
+	}
+	return report
+}
+
+// CorrelateStateDir parses terraform config in dirname along with its local state file and
+// correlates them with CorrelateState
+func CorrelateStateDir(dirname string) (*StateReport, error) {
+	config, err := ParseDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	state, err := ParseStateFile(filepath.Join(dirname, StateFile))
+	if err != nil {
+		return nil, err
+	}
+	return CorrelateState(config, state), nil
+}
@@ -0,0 +1,56 @@
+package tfparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCorrelateStateDir(t *testing.T) {
+	report, err := CorrelateStateDir("testdata/state")
+	if err != nil {
+		t.Fatalf("CorrelateStateDir returned an error, %v", err)
+	}
+	var modules []string
+	for _, m := range report.Modules {
+		modules = append(modules, m.Address+" -> "+m.Call)
+	}
+	expected := []string{
+		`module.network["a"] -> network`,
+		`module.network["a"].module.subnets -> network`,
+		`module.network["b"] -> network`,
+		`module.web -> app`,
+	}
+	if !reflect.DeepEqual(modules, expected) {
+		t.Errorf("Unexpected modules %v, expected %v", modules, expected)
+	}
+	if !reflect.DeepEqual(report.NotApplied, []string{"dns"}) {
+		t.Errorf("Unexpected not applied module calls %v", report.NotApplied)
+	}
+	if len(report.Orphaned) != 1 || report.Orphaned[0].Address != "module.legacy" || report.Orphaned[0].Call != "" {
+		t.Errorf("Unexpected orphaned modules %+v", report.Orphaned)
+	}
+	if r := report.Modules[3].Resources[0]; r.Address() != "data.aws_ami.ubuntu" {
+		t.Errorf("Unexpected resource address %v", r.Address())
+	}
+}
+
+func TestParseState(t *testing.T) {
+	state, err := ParseStateFile("testdata/state/" + StateFile)
+	if err != nil {
+		t.Fatalf("ParseStateFile returned an error, %v", err)
+	}
+	modules := state.Modules()
+	if len(modules) != 6 || len(modules[""]) != 1 || modules[""][0].Address() != "aws_s3_bucket.logs" {
+		t.Errorf("Unexpected modules %v", modules)
+	}
+	subnets := modules[`module.network["a"].module.subnets`]
+	if len(subnets) != 1 || len(subnets[0].Instances) != 2 || subnets[0].Instances[1].IndexKey != float64(1) {
+		t.Errorf("Unexpected subnets %+v", subnets)
+	}
+
+	for _, data := range []string{`{"version": 3, "resources": []}`, `{"version": 4`} {
+		if _, err := ParseState([]byte(data)); err == nil {
+			t.Errorf("ParseState did not return an error for %#q", data)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package tfparser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TerragruntFile is the name of terragrunt configuration file
+const TerragruntFile = "terragrunt.hcl"
+
+// TerragruntConfig represents terragrunt configuration file merged with the files it includes
+type TerragruntConfig struct {
+	Filename     string                           `json:"filename"`
+	Source       string                           `json:"source,omitempty"` // 'source' of 'terraform' block
+	Inputs       map[string]string                `json:"inputs,omitempty"` // expressions as they are written, strings unquoted
+	InputsPos    Pos                              `json:"inputs_pos"`
+	Locals       map[string]string                `json:"locals,omitempty"` // locals of the file itself, they are not inherited
+	Includes     []*TerragruntInclude             `json:"includes,omitempty"`
+	Dependencies map[string]*TerragruntDependency `json:"dependencies,omitempty"` // keyed by name, or by path for 'dependencies' block, one per directory
+}
+
+// TerragruntInclude represents 'include' block
+type TerragruntInclude struct {
+	Name string `json:"name,omitempty"` // empty for include block without label
+	Path string `json:"path"`           // path of included file
+	Pos  Pos    `json:"pos"`
+}
+
+// TerragruntDependency represents 'dependency' block or an entry of 'paths' of 'dependencies' block
+type TerragruntDependency struct {
+	Name       string `json:"name"`
+	ConfigPath string `json:"config_path"` // directory of the dependency
+	Pos        Pos    `json:"pos"`
+}
+
+// ParseTerragrunt parses terragrunt configuration file filename along with the files it includes.
+// Source, inputs and dependencies are inherited from included files, unless filename overrides them.
+// Paths are resolved relative to the file they are written in, 'find_in_parent_folders()' and
+// 'get_terragrunt_dir()' functions are supported in paths
+func ParseTerragrunt(filename string) (*TerragruntConfig, error) {
+	return parseTerragrunt(filepath.Clean(filename), nil)
+}
+
+// parseTerragrunt parses filename, chain is the list of files including it
+func parseTerragrunt(filename string, chain []string) (*TerragruntConfig, error) {
+	for _, f := range chain {
+		if f == filename {
+			return nil, fmt.Errorf("Include cycle found: %v -> %v", strings.Join(chain, " -> "), filename)
+		}
+	}
+	body, err := ParseBodyFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(filename)
+	config := &TerragruntConfig{Filename: filename, Dependencies: make(map[string]*TerragruntDependency)}
+	var listed []*TerragruntDependency // entries of 'dependencies' block, added after named dependencies
+
+	for _, b := range body.Blocks {
+		switch b.Type {
+		case "terraform":
+			config.Source = b.Attributes["source"]
+		case "locals":
+			config.Locals = b.Attributes
+		case "include":
+			include := &TerragruntInclude{Pos: b.Pos}
+			if len(b.Labels) > 0 {
+				include.Name = b.Labels[0]
+			}
+			if include.Path, err = resolveTerragruntPath(b.Attributes["path"], dir); err != nil {
+				return nil, fmt.Errorf("%v: %v", b.Pos, err)
+			}
+			config.Includes = append(config.Includes, include)
+		case "dependency":
+			if len(b.Labels) != 1 {
+				return nil, fmt.Errorf("%v: Dependency block must have a name", b.Pos)
+			}
+			path, err := resolveTerragruntPath(b.Attributes["config_path"], dir)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", b.Pos, err)
+			}
+			config.addDependency(&TerragruntDependency{b.Labels[0], path, b.Pos})
+		case "dependencies":
+			p := newParser(b.Attributes["paths"], filename, &TFconfig{})
+			paths, err := p.popList()
+			if err != nil {
+				return nil, fmt.Errorf("%v: Invalid paths of dependencies: %v", b.Pos, err)
+			}
+			for _, path := range paths {
+				resolved, err := resolveTerragruntPath(path, dir)
+				if err != nil {
+					return nil, fmt.Errorf("%v: %v", b.Pos, err)
+				}
+				listed = append(listed, &TerragruntDependency{path, resolved, b.AttributesPos["paths"]})
+			}
+		}
+	}
+	for _, dep := range listed {
+		config.addDependency(dep)
+	}
+	if inputs, exists := body.Attributes["inputs"]; exists {
+		config.InputsPos = body.AttributesPos["inputs"]
+		// inputs which are not object literals, e.g. 'merge(...)', are not read
+		if strings.HasPrefix(inputs, "{") {
+			p := newParser(inputs, filename, &TFconfig{})
+			attrs, _, err := p.popAttributes(nil)
+			if err != nil {
+				return nil, fmt.Errorf("%v: Invalid inputs: %v", config.InputsPos, err)
+			}
+			config.Inputs = make(map[string]string)
+			for name, expr := range attrs {
+				config.Inputs[name] = unquote(expr)
+			}
+		}
+	}
+
+	for _, include := range config.Includes {
+		parent, err := parseTerragrunt(include.Path, append(chain, filename))
+		if err != nil {
+			return nil, err
+		}
+		config.inherit(parent)
+	}
+	return config, nil
+}
+
+// inherit copies source, inputs and dependencies of parent, which are not set in c
+func (c *TerragruntConfig) inherit(parent *TerragruntConfig) {
+	if c.Source == "" {
+		c.Source = parent.Source
+	}
+	if len(parent.Inputs) > 0 && c.Inputs == nil {
+		c.Inputs = make(map[string]string)
+		c.InputsPos = parent.InputsPos
+	}
+	for name, value := range parent.Inputs {
+		if _, exists := c.Inputs[name]; !exists {
+			c.Inputs[name] = value
+		}
+	}
+	for _, name := range sortedDependencyNames(parent.Dependencies) {
+		c.addDependency(parent.Dependencies[name])
+	}
+}
+
+// addDependency adds dep, unless c already has a dependency with the same name or directory
+func (c *TerragruntConfig) addDependency(dep *TerragruntDependency) {
+	if _, exists := c.Dependencies[dep.Name]; exists {
+		return
+	}
+	for _, d := range c.Dependencies {
+		if d.ConfigPath == dep.ConfigPath {
+			return
+		}
+	}
+	c.Dependencies[dep.Name] = dep
+}
+
+func sortedDependencyNames(deps map[string]*TerragruntDependency) []string {
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var findInParentFoldersRe = regexp.MustCompile(`^find_in_parent_folders\(\s*(?:"([^"]*)")?\s*\)$`)
+
+// resolveTerragruntPath resolves path expression expr written in a file of directory dir
+func resolveTerragruntPath(expr, dir string) (string, error) {
+	path := strings.TrimSpace(unquote(strings.TrimSpace(expr)))
+	if strings.HasPrefix(path, "${") && strings.HasSuffix(path, "}") && scanTemplate(path, 1) == len(path) {
+		path = strings.TrimSpace(path[2 : len(path)-1])
+	}
+	if m := findInParentFoldersRe.FindStringSubmatch(path); m != nil {
+		name := m[1]
+		if name == "" {
+			name = TerragruntFile
+		}
+		for d := filepath.Dir(dir); ; d = filepath.Dir(d) {
+			candidate := filepath.Join(d, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+			if parent := filepath.Dir(d); parent == d {
+				break
+			}
+		}
+		return "", fmt.Errorf("Unable to find %#q in parent folders of %v", name, dir)
+	}
+	// get_terragrunt_dir() is dir itself, so the path is not relative to dir anymore
+	inDir := strings.HasPrefix(path, "${get_terragrunt_dir()}")
+	path = strings.ReplaceAll(path, "${get_terragrunt_dir()}", dir)
+	if path == "" || strings.Contains(path, "${") || strings.Contains(path, "(") {
+		return "", fmt.Errorf("Unable to resolve path %#q", expr)
+	}
+	if !filepath.IsAbs(path) && !inDir {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// ParseTerragruntDir finds all terragrunt configuration files in dirname and its subdirectories
+// and returns them as module calls: module name is the directory of the file relative to
+// dirname, source is terraform source and parameters are inputs. Local sources are made relative
+// to dirname, so called modules can be parsed with ParseModules. Dependencies are references
+// between modules, see NewGraph. Files which are only included by others are not module calls,
+// hidden directories, e.g. .terragrunt-cache, are skipped
+func ParseTerragruntDir(dirname string) (*TFconfig, error) {
+	var files []string
+	err := filepath.Walk(dirname, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != dirname && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && info.Name() == TerragruntFile {
+			files = append(files, filepath.Clean(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make(map[string]*TerragruntConfig)
+	included := make(map[string]bool)
+	for _, filename := range files {
+		c, err := ParseTerragrunt(filename)
+		if err != nil {
+			return nil, err
+		}
+		configs[filename] = c
+		for _, include := range c.Includes {
+			included[include.Path] = true
+		}
+	}
+
+	names := make(map[string]string) // directory to module name
+	for _, filename := range files {
+		if !included[filename] {
+			names[filepath.Dir(filename)] = terragruntModuleName(dirname, filepath.Dir(filename))
+		}
+	}
+	config := &TFconfig{Modules: make(map[string]*Module), Objects: make(map[string]*Object)}
+	for _, filename := range files {
+		name, exists := names[filepath.Dir(filename)]
+		if !exists {
+			continue
+		}
+		c := configs[filename]
+		m := &Module{
+			Providers:     make(map[string]string),
+			Parameters:    make(map[string]string),
+			SourcePath:    terragruntSource(dirname, filepath.Dir(filename), c.Source),
+			Pos:           Pos{filename, 1, 1},
+			ProvidersPos:  make(map[string]Pos),
+			ParametersPos: make(map[string]Pos),
+		}
+		for input, value := range c.Inputs {
+			m.Parameters[input] = value
+			m.ParametersPos[input] = c.InputsPos
+		}
+		config.Modules[name] = m
+
+		var refs []string
+		for _, dep := range c.Dependencies {
+			if depName, exists := names[dep.ConfigPath]; exists {
+				refs = append(refs, "module."+depName)
+			}
+		}
+		sort.Strings(refs)
+		config.Objects["module."+name] = &Object{"module." + name, refs, m.Pos}
+	}
+	return config, nil
+}
+
+// terragruntModuleName returns name of module call for terragrunt configuration in dir
+func terragruntModuleName(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		abs, _ := filepath.Abs(dir)
+		return filepath.Base(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// terragruntSource makes local source of terragrunt configuration in dir relative to root.
+// Subdirectory of the source after '//', e.g. '../modules//vpc', is kept as it is
+func terragruntSource(root, dir, source string) string {
+	if !isLocalSource(source) {
+		return source
+	}
+	base, subdir := source, ""
+	if i := strings.Index(source, "//"); i >= 0 {
+		base, subdir = source[:i], source[i:]
+	}
+	rel, err := filepath.Rel(root, filepath.Join(dir, base))
+	if err != nil {
+		return source
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel + subdir
+}
@@ -0,0 +1,88 @@
+package tfparser
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseTerragrunt(t *testing.T) {
+	c, err := ParseTerragrunt("testdata/terragrunt/live/app/terragrunt.hcl")
+	if err != nil {
+		t.Fatalf("ParseTerragrunt returned an error, %v", err)
+	}
+	if c.Source != "git::https://example.com/modules.git//app?ref=v1.0.0" {
+		t.Errorf("Unexpected source %v", c.Source)
+	}
+	if len(c.Includes) != 1 || c.Includes[0].Path != filepath.Clean("testdata/terragrunt/live/terragrunt.hcl") {
+		t.Errorf("Unexpected includes %+v", c.Includes)
+	}
+	inputs := map[string]string{
+		"vpc_id":      "dependency.vpc.outputs.vpc_id",
+		"environment": "staging",
+		"region":      "eu-west-1",
+	}
+	if !reflect.DeepEqual(c.Inputs, inputs) {
+		t.Errorf("Unexpected inputs %v, expected %v", c.Inputs, inputs)
+	}
+	if !reflect.DeepEqual(c.Locals, map[string]string{"name": "app"}) {
+		t.Errorf("Unexpected locals %v", c.Locals)
+	}
+	vpc := filepath.Clean("testdata/terragrunt/live/vpc")
+	if dep := c.Dependencies["vpc"]; dep == nil || dep.ConfigPath != vpc || dep.Pos.Line != 13 {
+		t.Errorf("Unexpected dependency %+v", dep)
+	}
+	// dependencies block lists the same directory as dependency block
+	if len(c.Dependencies) != 1 {
+		t.Errorf("Unexpected dependencies %v", c.Dependencies)
+	}
+}
+
+func TestParseTerragruntDir(t *testing.T) {
+	config, err := ParseTerragruntDir("testdata/terragrunt/live")
+	if err != nil {
+		t.Fatalf("ParseTerragruntDir returned an error, %v", err)
+	}
+	if len(config.Modules) != 2 || config.Modules["app"] == nil || config.Modules["vpc"] == nil {
+		t.Fatalf("Unexpected modules %v", config.Modules)
+	}
+	vpc := config.Modules["vpc"]
+	if vpc.SourcePath != "../modules//vpc" || vpc.Parameters["cidr"] != "10.0.0.0/16" || vpc.Parameters["environment"] != "prod" {
+		t.Errorf("Unexpected module vpc %+v", vpc)
+	}
+	if refs := config.Objects["module.app"].References; !reflect.DeepEqual(refs, []string{"module.vpc"}) {
+		t.Errorf("Unexpected references of app %v", refs)
+	}
+
+	modules, err := ParseModules(config, "testdata/terragrunt/live")
+	if err != nil {
+		t.Fatalf("ParseModules returned an error, %v", err)
+	}
+	if len(modules) != 1 || modules["vpc"] == nil || modules["vpc"].Objects["var.cidr"] == nil {
+		t.Errorf("Unexpected called modules %v", modules)
+	}
+	if deps := NewGraph(config).Dependencies("module.app"); !reflect.DeepEqual(deps, []string{"module.vpc"}) {
+		t.Errorf("Unexpected dependencies of app %v", deps)
+	}
+}
+
+func TestParseTerragruntErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, code := range map[string]string{
+		"cycle.hcl":   `include { path = "cycle2.hcl" }`,
+		"cycle2.hcl":  `include { path = "cycle.hcl" }`,
+		"missing.hcl": `include { path = find_in_parent_folders("missing.hcl") }`,
+		"dynamic.hcl": `include { path = local.path }`,
+		"unnamed.hcl": "dependency {\n config_path = \"../vpc\"\n}",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"cycle.hcl", "missing.hcl", "dynamic.hcl", "unnamed.hcl"} {
+		if _, err := ParseTerragrunt(filepath.Join(dir, name)); err == nil {
+			t.Errorf("ParseTerragrunt did not return an error for %v", name)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+provider "aws" {
+  region = "us-east-1"
+  alias  = "assumed"
+
+  assume_role {
+    role_arn = "arn:aws:iam::123456789012:role/deploy"
+  }
+}
+
+resource "aws_security_group" "web" {
+  name = "web"
+
+  ingress {
+    from_port = 443
+    to_port   = 443
+  }
+
+  dynamic "ingress" {
+    for_each = var.ports
+    content {
+      from_port = ingress.value
+      to_port   = ingress.value
+    }
+  }
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+variable "ports" { default = [80, 8080] }
+
+module "legacy" {
+  source = "./legacy"
+
+  experimental "feature" {
+    enabled = true
+  }
+}
+
+terraform {
+  required_version = ">= 1.0"
+
+  required_providers {
+    aws = "~> 5.0"
+  }
+
+  backend "s3" {
+    bucket = "state"
+    key    = "app/\"main\".tfstate"
+  }
+}
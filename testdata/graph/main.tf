@@ -0,0 +1,41 @@
+variable "region" {
+  default = "us-east-1"
+}
+
+variable "env" {
+  default = "dev"
+}
+
+locals {
+  name = "${var.env}-vpc" # comment with var.region is not a reference
+  tags = {
+    Environment = var.env
+  }
+}
+
+data "aws_ami" "ubuntu" {
+  most_recent = true
+}
+
+resource "aws_instance" "bastion" {
+  ami       = data.aws_ami.ubuntu.id
+  subnet_id = module.vpc.public_subnets[0]
+  tags      = local.tags
+}
+
+module "vpc" {
+  source = "../../modules/vpc"
+  name   = local.name
+  region = var.region
+}
+
+module "peering" {
+  source     = "../../modules/vpc-peering"
+  vpc_id     = module.vpc.vpc_id
+  azs        = [for az in ["a", "b"] : "${var.region}${az}"]
+  depends_on = [aws_instance.bastion]
+}
+
+output "vpc_id" {
+  value = module.vpc.vpc_id
+}
@@ -0,0 +1,15 @@
+variable "name" {}
+
+variable "cidr" {}
+
+variable "zones" {
+  default = []
+}
+
+variable "nat_ip" {
+  default = null
+}
+
+variable "tags" {
+  default = { managed = "tfparser" }
+}
@@ -0,0 +1,43 @@
+variable "env" {}
+
+variable "cidr" {
+  default = "10.0.0.0/16"
+}
+
+variable "zones" {
+  default = ["a", "b"]
+}
+
+locals {
+  name   = "${local.prefix} VPC"
+  prefix = title(var.env)
+}
+
+resource "aws_eip" "nat" {}
+
+module "vpc" {
+  source  = "../modules/vpc"
+  version = "1.0.0"
+
+  name       = local.name
+  cidr       = cidrsubnet(var.cidr, 4, 1)
+  zones      = [for z in var.zones : "us-east-1${z}"]
+  nat_ip     = aws_eip.nat.public_ip
+  depends_on = [aws_eip.nat]
+}
+
+module "peer" {
+  source   = "../modules/vpc"
+  for_each = toset(var.zones)
+
+  name = "peer-${each.key}"
+  cidr = "10.1.0.0/16"
+}
+
+module "subnet" {
+  source = "../modules/vpc"
+  count  = length(var.zones)
+
+  name = "subnet-${count.index}"
+  cidr = cidrsubnet(var.cidr, 8, count.index)
+}
@@ -0,0 +1,61 @@
+provider "aws" {
+  region = "us-east-1"
+}
+
+provider "aws" {
+  alias  = "unused"
+  region = "eu-west-1"
+}
+
+provider "aws" { # tfparser:ignore unused-provider
+  alias  = "ignored"
+  region = "eu-west-2"
+}
+
+# tfparser:ignore unused-provider
+provider "aws" {
+  alias  = "us-east-1"
+  region = "us-east-1"
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 3.0"
+  name    = "main"
+  region  = "us-east-1"
+}
+
+module "vpc_copy" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 3.0"
+  name    = "main"
+  region  = "us-east-1" # tfparser:ignore
+}
+
+module "routing" {
+  source = "git::https://example.com/vpc-routing.git"
+}
+
+module "routing_pinned" {
+  source = "git::https://example.com/vpc-routing.git?ref=v1.2.0"
+}
+
+module "local" {
+  source = "../../modules/local"
+  region = "ap-southeast-2"
+}
+
+module "vpc_other" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "3.0.0"
+  name    = "main"
+  cidr    = "10.1.0.0/16"
+  azs     = var.azs
+}
+
+module "vpc_shared" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "3.0.0"
+  azs     = var.azs
+  cidr    = "10.2.0.0/16"
+}
@@ -0,0 +1,18 @@
+terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 4.0"
+    }
+    random = {
+      source  = "hashicorp/random"
+      version = ">= 3.1, < 4.0"
+    }
+    tls = {
+      version = "4.0.4"
+    }
+    github = {
+      source = "integrations/github"
+    }
+  }
+}
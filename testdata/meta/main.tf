@@ -0,0 +1,53 @@
+module "vpc" {
+  source = "../../modules/vpc"
+}
+
+resource "aws_instance" "web" {
+  count = 2
+}
+
+# module renamed from 'network'
+moved {
+  from = module.network
+  to   = module.vpc
+}
+
+moved {
+  from = aws_instance.web
+  to   = aws_instance.web[0]
+}
+
+moved {
+  from = module.vpc
+  to   = module.missing
+}
+
+moved {
+  from = module.old
+  to   = aws_instance.web
+}
+
+import {
+  to       = aws_instance.web[1]
+  id       = "i-0123456789"
+  provider = aws.alice
+}
+
+removed {
+  from = module.legacy
+
+  lifecycle {
+    destroy = false
+  }
+}
+
+check "health" {
+  data "http" "status" {
+    url = "https://example.com/health"
+  }
+
+  assert {
+    condition     = data.http.status.status_code == 200
+    error_message = "Service \"web\" is down"
+  }
+}
@@ -0,0 +1,15 @@
+module "module1" {
+  source = "./modules/vpc"
+}
+
+module "module2" {
+  source = "./modules/app"
+}
+
+module "module3" {
+  source = "./modules/dns"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
@@ -0,0 +1,15 @@
+terraform {
+  required_version = ">= 1.0"
+
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      version               = ">= 3.0"
+      configuration_aliases = [aws.alice, aws.bob]
+    }
+  }
+}
+
+resource "aws_route" "alice" {
+  provider = aws.alice
+}
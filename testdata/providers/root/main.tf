@@ -0,0 +1,31 @@
+provider "aws" {
+  region = "us-east-1"
+  alias  = "us-east-1"
+}
+
+provider "aws" {
+  region = "ap-southeast-2"
+  alias  = "ap-southeast-2"
+
+  assume_role {
+    role_arn = "arn:aws:iam::123456789012:role/terraform"
+  }
+}
+
+module "routing_ok" {
+  source = "../modules/routing"
+
+  providers = {
+    aws.alice = aws.us-east-1
+    aws.bob   = aws.ap-southeast-2
+  }
+}
+
+module "routing_broken" {
+  source = "../modules/routing"
+
+  providers = {
+    aws.alice   = aws.eu-west-1
+    aws.charlie = aws.ap-southeast-2
+  }
+}
@@ -0,0 +1,21 @@
+module "network" {
+  source   = "./modules/vpc"
+  for_each = toset(["a", "b"])
+}
+
+module "app" {
+  source = "./modules/app"
+}
+
+module "dns" {
+  source = "./modules/dns"
+}
+
+moved {
+  from = module.web
+  to   = module.app
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
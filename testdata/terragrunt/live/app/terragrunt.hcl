@@ -0,0 +1,28 @@
+include {
+  path = "${find_in_parent_folders()}"
+}
+
+locals {
+  name = "app"
+}
+
+terraform {
+  source = "git::https://example.com/modules.git//app?ref=v1.0.0"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+
+  mock_outputs = {
+    vpc_id = "vpc-mock"
+  }
+}
+
+dependencies {
+  paths = ["${get_terragrunt_dir()}/../vpc"]
+}
+
+inputs = {
+  vpc_id      = dependency.vpc.outputs.vpc_id
+  environment = "staging"
+}
@@ -0,0 +1,12 @@
+remote_state {
+  backend = "s3"
+  config = {
+    bucket = "state"
+    key    = "${path_relative_to_include()}/terraform.tfstate"
+  }
+}
+
+inputs = {
+  region      = "eu-west-1"
+  environment = "prod"
+}